@@ -1,27 +1,140 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/todo-list/internal/auth"
 	"github.com/ZnNr/todo-list/internal/database"
 	"github.com/ZnNr/todo-list/internal/handlers"
+	"github.com/ZnNr/todo-list/internal/ratelimit"
 	"github.com/ZnNr/todo-list/internal/router"
 	"github.com/ZnNr/todo-list/internal/settings"
 	"github.com/ZnNr/todo-list/internal/task"
-	_ "github.com/lib/pq"
 	"log"
+	"os"
+	"strconv"
 )
 
+const usage = `Usage:
+  todo-list                   start the server (pending migrations are applied first)
+  todo-list migrate [up [N]]  apply all or N pending migrations
+  todo-list migrate down [N]  roll back the last or N last migrations
+  todo-list migrate status    show migration status`
+
 func main() {
 	// Инициализация базы данных и задач.
-	dbFile := settings.Setting("TODO_DBFILE")
-	taskData, dbErr := database.NewTaskData(dbFile)
-	defer taskData.CloseDb()
+	taskData, dbErr := database.NewTaskData(settings.DataSource())
 	if dbErr != nil {
 		log.Fatalf("Error initializing task data: %v", dbErr)
 	}
+	defer taskData.CloseDb()
+
+	if len(os.Args) > 1 {
+		if err := runCommand(taskData, os.Args[1:]); err != nil {
+			taskData.CloseDb()
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Применение миграций схемы.
+	if err := taskData.Migrate(context.Background()); err != nil {
+		taskData.CloseDb()
+		log.Fatalf("Error migrating database: %v", err)
+	}
 
 	// Инициализация службы задач.
 	handlers.TaskServiceInstance = task.InitTaskService(taskData)
 
+	// Фоновая очистка корзины.
+	retention, err := settings.TrashRetention()
+	if err != nil {
+		taskData.CloseDb()
+		log.Fatalf("Invalid TODO_TRASH_RETENTION: %v", err)
+	}
+	go handlers.TaskServiceInstance.RunTrashRetention(context.Background(), retention, settings.TrashPurgeInterval)
+
+	// Вход по паролю TODO_PASSWORD и по логинам пользователей.
+	ttl, err := settings.TokenTTL()
+	if err != nil {
+		taskData.CloseDb()
+		log.Fatalf("Invalid TODO_TOKEN_TTL: %v", err)
+	}
+	handlers.AuthInstance, err = auth.New(settings.Setting("TODO_PASSWORD"), []byte(settings.Setting("TODO_TOKEN_SECRET")), ttl)
+	if err != nil {
+		taskData.CloseDb()
+		log.Fatalf("Error initializing authentication: %v", err)
+	}
+
+	// Ограничение частоты запросов клиентов.
+	limits := map[string]ratelimit.Limit{}
+	for group, value := range settings.RateLimits() {
+		if limits[group], err = ratelimit.ParseLimit(value); err != nil {
+			taskData.CloseDb()
+			log.Fatalf("Invalid rate limit for group %q: %v", group, err)
+		}
+	}
+	handlers.RateLimiterInstance = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), limits)
+
 	// Инициализация маршрутизатора и запуск сервера.
 	router.StartServer()
 }
+
+// runCommand выполняет подкоманду командной строки
+func runCommand(taskData *database.TaskData, args []string) error {
+	if args[0] != "migrate" {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	return migrate(taskData, args[1:])
+}
+
+// migrate выполняет подкоманду migrate
+func migrate(taskData *database.TaskData, args []string) error {
+	ctx := context.Background()
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of steps %q", args[1])
+		}
+		steps = n
+	}
+
+	switch action {
+	case "up":
+		applied, err := taskData.MigrateUp(ctx, steps)
+		for _, m := range applied {
+			log.Printf("applied %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Print("no pending migrations")
+		}
+		return err
+	case "down":
+		reverted, err := taskData.MigrateDown(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("reverted %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			log.Print("no applied migrations")
+		}
+		return err
+	case "status":
+		states, err := taskData.MigrationStatus(ctx)
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", action, usage)
+	}
+}
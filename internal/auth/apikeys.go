@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix начало ключей API, по которому они отличаются от токенов доступа
+const APIKeyPrefix = "todo_"
+
+// apiKeyVisible число символов ключа после APIKeyPrefix, которые остаются видны в списке ключей
+const apiKeyVisible = 8
+
+// NewAPIKey возвращает новый случайный ключ API и его начало для отображения в списке ключей
+func NewAPIKey() (key, prefix string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:len(APIKeyPrefix)+apiKeyVisible], nil
+}
+
+// IsAPIKey проверяет, является ли token ключом API, а не токеном доступа
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// HashAPIKey возвращает хеш SHA-256 ключа API. Ключ случайный и длинный, поэтому подобрать его по хешу нельзя,
+// и медленный хеш, как для паролей, не нужен: по быстрому хешу ключ находится при каждом запросе.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,138 @@
+// Package auth выдает и проверяет токены доступа к API.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// tokenHeader заголовок JWT, подписанного HMAC-SHA256
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// claims полезная нагрузка токена. Subject - пользователь, которому выдан токен.
+// Password - отпечаток пароля, с которым выдан токен: после смены пароля отпечаток не совпадет,
+// и все выданные токены станут недействительны.
+type claims struct {
+	Subject   int64  `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Password  string `json:"pwd"`
+}
+
+// Authenticator выдает токены и проверяет их. Токен пользователя по умолчанию выдается по паролю TODO_PASSWORD,
+// токены зарегистрированных пользователей - после проверки логина и пароля, см. Issue.
+type Authenticator struct {
+	password string
+	secret   []byte
+	ttl      time.Duration
+}
+
+// New создает Authenticator для пароля password. Токены подписываются ключом secret и действуют ttl.
+// Без ключа он создается случайным, и выданные токены перестают действовать после перезапуска сервера.
+// Пустой пароль отключает проверку токенов.
+func New(password string, secret []byte, ttl time.Duration) (*Authenticator, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &Authenticator{password: password, secret: secret, ttl: ttl}, nil
+}
+
+// Enabled проверяет, требуется ли токен для доступа к API. Без пароля запросы без токена выполняются
+// от имени пользователя по умолчанию, а токены зарегистрированных пользователей принимаются всегда.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.password) > 0
+}
+
+// SignIn проверяет пароль TODO_PASSWORD и возвращает новый токен пользователя по умолчанию
+// и время, до которого он действует
+func (a *Authenticator) SignIn(password string) (string, time.Time, error) {
+	if !a.Enabled() || subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) != 1 {
+		return "", time.Time{}, taskerror.ErrInvalidPassword
+	}
+	return a.issue(model.DefaultUserId, a.password)
+}
+
+// Issue возвращает новый токен пользователя, пароль которого уже проверен, и время, до которого он действует.
+// Токен действует, пока не изменится хеш пароля пользователя.
+func (a *Authenticator) Issue(user model.User) (string, time.Time, error) {
+	return a.issue(user.Id, user.PasswordHash)
+}
+
+// issue возвращает токен пользователя userID с отпечатком credential
+func (a *Authenticator) issue(userID int64, credential string) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(a.ttl)
+	payload, err := json.Marshal(claims{
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+		Password:  a.fingerprint(credential),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + a.sign(unsigned), expires, nil
+}
+
+// Verify проверяет подпись и срок действия токена и что он выдан для текущего пароля пользователя,
+// и возвращает ID пользователя. Хеш пароля зарегистрированного пользователя возвращает credential.
+func (a *Authenticator) Verify(token string, credential func(userID int64) (string, error)) (int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return 0, taskerror.ErrUnauthorized
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(a.sign(parts[0]+"."+parts[1]))) {
+		return 0, taskerror.ErrUnauthorized
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, taskerror.ErrUnauthorized
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Subject <= 0 {
+		return 0, taskerror.ErrUnauthorized
+	}
+
+	current := a.password
+	if c.Subject != model.DefaultUserId {
+		if current, err = credential(c.Subject); err != nil {
+			return 0, err
+		}
+	}
+	if len(current) == 0 || !hmac.Equal([]byte(c.Password), []byte(a.fingerprint(current))) {
+		return 0, taskerror.ErrUnauthorized
+	}
+	if time.Now().Unix() >= c.ExpiresAt {
+		return 0, taskerror.ErrTokenExpired
+	}
+	return c.Subject, nil
+}
+
+// sign возвращает подпись HMAC-SHA256 заголовка и полезной нагрузки токена
+func (a *Authenticator) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// fingerprint возвращает отпечаток пароля или его хеша credential. Он вычисляется с ключом подписи,
+// поэтому по токену нельзя подобрать пароль, не зная ключа.
+func (a *Authenticator) fingerprint(credential string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte("password:" + credential))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	apiKeyColumns  = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at"
+	insertKeyQuery = `
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+	getKeyQuery       = "SELECT " + apiKeyColumns + " FROM api_keys WHERE id = ? AND user_id = ?"
+	getKeyByHashQuery = "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = ?"
+	keysQuery         = "SELECT " + apiKeyColumns + " FROM api_keys WHERE user_id = ? ORDER BY id DESC"
+	deleteKeyQuery    = "DELETE FROM api_keys WHERE id = ? AND user_id = ?"
+
+	// touchKeyQuery отмечает использование ключа не чаще раза в keyTouchInterval,
+	// чтобы каждый запрос по ключу не изменял базу данных
+	touchKeyQuery = "UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)"
+)
+
+// keyTouchInterval точность времени последнего использования ключа API
+const keyTouchInterval = time.Minute
+
+// APIKeyStore описывает хранилище ключей API пользователей
+type APIKeyStore interface {
+	InsertAPIKey(ctx context.Context, key model.APIKey) (int64, error)
+	GetAPIKey(ctx context.Context, id int64) (model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error)
+	FindAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id int64) (bool, error)
+	TouchAPIKey(ctx context.Context, id int64) error
+}
+
+// InsertAPIKey создает ключ API пользователя и возвращает его ID. Сохраняется только хеш ключа.
+func (data *TaskData) InsertAPIKey(ctx context.Context, key model.APIKey) (int64, error) {
+	return data.dialect.insert(ctx, data.db, insertKeyQuery, OwnerFrom(ctx), key.Name, key.Prefix, key.KeyHash,
+		strings.Join(key.Scopes, " "), nullTime(key.ExpiresAt), time.Now().UTC())
+}
+
+// GetAPIKey возвращает ключ API пользователя по ID
+func (data *TaskData) GetAPIKey(ctx context.Context, id int64) (model.APIKey, error) {
+	return scanAPIKey(data.db.QueryRowContext(ctx, data.q(getKeyQuery), id, OwnerFrom(ctx)))
+}
+
+// GetAPIKeyByHash возвращает ключ API любого пользователя по хешу ключа
+func (data *TaskData) GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	return scanAPIKey(data.db.QueryRowContext(ctx, data.q(getKeyByHashQuery), hash))
+}
+
+// FindAPIKeys возвращает ключи API пользователя от новых к старым
+func (data *TaskData) FindAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := data.db.QueryContext(ctx, data.q(keysQuery), OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey отзывает ключ API пользователя. false означает, что такого ключа у пользователя нет.
+func (data *TaskData) DeleteAPIKey(ctx context.Context, id int64) (bool, error) {
+	res, err := data.db.ExecContext(ctx, data.q(deleteKeyQuery), id, OwnerFrom(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// TouchAPIKey сохраняет время последнего использования ключа API с точностью до keyTouchInterval
+func (data *TaskData) TouchAPIKey(ctx context.Context, id int64) error {
+	now := time.Now().UTC()
+	_, err := data.db.ExecContext(ctx, data.q(touchKeyQuery), now, id, now.Add(-keyTouchInterval))
+	return err
+}
+
+// scanAPIKey считывает ключ API в порядке apiKeyColumns
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	var (
+		key                              model.APIKey
+		scopes                           string
+		expiresAt, lastUsedAt, createdAt sql.NullTime
+	)
+	err := row.Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&expiresAt, &lastUsedAt, &createdAt)
+	key.Scopes = strings.Fields(scopes)
+	key.ExpiresAt, key.LastUsedAt, key.CreatedAt = timePtr(expiresAt), timePtr(lastUsedAt), timePtr(createdAt)
+	return key, err
+}
@@ -1,55 +1,122 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"strings"
+	"time"
 
+	taskerror "github.com/ZnNr/todo-list/internal/error"
 	"github.com/ZnNr/todo-list/internal/model"
-	_ "modernc.org/sqlite"
+	"github.com/ZnNr/todo-list/internal/settings"
 )
 
 const (
-	driverName = "sqlite"
-
-	tableSchema = `
-CREATE TABLE IF NOT EXISTS todolist (
-    id INTEGER PRIMARY KEY,
-    date VARCHAR(8),
-    title TEXT,
-    description TEXT, 
-    status TEXT
-);
-`
-	indexSchema = `
-CREATE INDEX IF NOT EXISTS indexdate ON todolist (date);
-`
+	taskColumns = "id, date, title, description, status, repeat, created_at, updated_at, completed_at, version, deleted_at, priority, due_time, project_id, sort_rank, parent_id, owner_id"
+
 	insertQuery = `
-INSERT INTO scheduler(date, title, description) VALUES (?, ?, ?)
+INSERT INTO todolist (date, title, description, status, repeat, created_at, updated_at, completed_at, priority, due_time,
+    project_id, parent_id, sort_rank, owner_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+	// visibleTask отбирает задачи, доступные пользователю из плейсхолдера: его собственные задачи во Входящих
+	// и задачи проектов, которыми он владеет или в которых участвует
+	visibleTask = "id IN (SELECT task_id FROM task_access WHERE user_id = ?)"
+
+	getTaskQuery = "SELECT " + taskColumns + " FROM todolist WHERE id = ? AND " + visibleTask + " AND deleted_at IS NULL"
+
+	updateQuery = `
+UPDATE todolist SET date = ?, title = ?, description = ?, status = ?, repeat = ?, updated_at = ?, completed_at = ?,
+    priority = ?, due_time = ?, parent_id = ?, version = version + 1,
+    project_id = COALESCE(NULLIF(?, 0), project_id),
+    sort_rank = CASE WHEN COALESCE(NULLIF(?, 0), project_id) = project_id THEN sort_rank ELSE ? END
+WHERE id = ? AND ` + visibleTask + ` AND version = ? AND deleted_at IS NULL
 `
-	getTaskQuery = "SELECT * FROM todolist WHERE id = ?"
-
-	getTasksQuery = "SELECT * FROM todolist ORDER BY date LIMIT ?"
 
-	getTasksByDateQuery          = "SELECT * FROM todolist WHERE date = ? ORDER BY date LIMIT ?"
-	getTasksByStatusQuery        = "SELECT * FROM todolist WHERE status = ? LIMIT ?"
-	getTasksByStatusAndDateQuery = "SELECT * FROM todolist WHERE status = ? AND date = ? ORDER BY date LIMIT ?"
+	// Удаление задачи переносит ее в корзину, окончательно удаляются только задачи из корзины
+	deleteQuery = `
+UPDATE todolist SET deleted_at = ?, version = version + 1
+WHERE id = ? AND ` + visibleTask + ` AND version = ? AND deleted_at IS NULL
+`
+	restoreQuery = `
+UPDATE todolist SET deleted_at = NULL, updated_at = ?, version = version + 1
+WHERE id = ? AND ` + visibleTask + ` AND deleted_at IS NOT NULL
+`
+	purgeQuery       = "DELETE FROM todolist WHERE id = ? AND " + visibleTask + " AND deleted_at IS NOT NULL"
+	trashBefore      = " FROM todolist WHERE deleted_at IS NOT NULL AND deleted_at < ?"
+	trashBeforeQuery = "SELECT " + taskColumns + trashBefore
+	trashCountQuery  = "SELECT COUNT(*)" + trashBefore
+
+	// purgerQuery выбирает пользователя, которому доступна задача проекта из плейсхолдера: владельца проекта,
+	// а во Входящих, где владельца нет, - владельца задачи из второго плейсхолдера
+	purgerQuery = "SELECT COALESCE(owner_id, ?) FROM projects WHERE id = ?"
+)
 
-	updateQuery = "UPDATE todolist SET date=?, title=?, description=?, WHERE id=?"
+// TaskStore описывает хранилище задач, не зависящее от конкретной СУБД.
+// Запросы к задачам, проектам, тегам и журналу выполняются от имени пользователя из контекста, см. WithOwner:
+// ему доступны его задачи и задачи проектов, в которых он участвует, остальные задачи для него не существуют.
+// Роли участников проверяет сервис задач.
+type TaskStore interface {
+	InsertTask(ctx context.Context, task model.Task) (int64, error)
+	GetTask(ctx context.Context, id int64) (model.Task, error)
+	FindTasks(ctx context.Context, filter model.TaskFilter) ([]model.Task, string, error)
+	UpdateTask(ctx context.Context, task model.Task, action string) (bool, error)
+	DeleteTask(ctx context.Context, id int64, version int64) (bool, error)
+	RestoreTask(ctx context.Context, id int64) (bool, error)
+	PurgeTask(ctx context.Context, id int64) (bool, error)
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+	FindSubtasks(ctx context.Context, id int64) ([]model.Task, error)
+	CompleteSubtasks(ctx context.Context, id int64, completedAt time.Time) (int64, error)
+	FindEvents(ctx context.Context, filter model.EventFilter) ([]model.TaskEvent, string, error)
+	UserStore
+	TagStore
+	ProjectStore
+	DependencyStore
+	MemberStore
+	APIKeyStore
+	GetRevision(ctx context.Context, id int64, revision int64) (model.TaskRevision, error)
+	FindRevisions(ctx context.Context, id int64) ([]model.TaskRevision, error)
+	CloseDb() error
+}
 
-	deleteQuery = "DELETE FROM todolist WHERE id=:id"
-)
+// queryer объединяет методы, общие для *sql.DB и *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
 // TaskData представляет структуру для работы с данными задач
 type TaskData struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
 }
 
-// NewTaskData создает новый экземпляр TaskData с подключением к базе данных
+var _ TaskStore = (*TaskData)(nil)
+
+// NewTaskData создает новый экземпляр TaskData, выбирая СУБД по строке подключения.
+// Строки вида postgres://... и postgresql://... открывают PostgreSQL,
+// все остальные (в том числе sqlite://path и просто путь к файлу) - SQLite.
 func NewTaskData(dataSourceName string) (*TaskData, error) {
-	db, err := openDb(dataSourceName)
+	if isPostgresDSN(dataSourceName) {
+		return NewPostgresTaskData(dataSourceName)
+	}
+	return NewSQLiteTaskData(dataSourceName)
+}
+
+// isPostgresDSN проверяет, указывает ли строка подключения на PostgreSQL
+func isPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// newTaskData открывает соединение с базой данных для указанного диалекта.
+// Схема базы данных создается миграциями, см. Migrate.
+func newTaskData(d dialect, dataSourceName string) (*TaskData, error) {
+	db, err := openDb(d, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
-	return &TaskData{db: db}, nil
+	return &TaskData{db: db, dialect: d}, nil
 }
 
 // CloseDb закрывает соединение с базой данных
@@ -57,33 +124,139 @@ func (data *TaskData) CloseDb() error {
 	return data.db.Close()
 }
 
-// InsertTask вставляет задачу в базу данных и возвращает ее ID
-func (data *TaskData) InsertTask(task model.Task) (int64, error) {
-	stmt, err := data.db.Prepare(insertQuery)
+// q приводит запрос к синтаксису плейсхолдеров текущего диалекта
+func (data *TaskData) q(query string) string {
+	return data.dialect.rebind(query)
+}
+
+// InsertTask вставляет задачу в базу данных и возвращает ее ID.
+// Время создания и изменения задачи устанавливается текущим.
+func (data *TaskData) InsertTask(ctx context.Context, task model.Task) (int64, error) {
+	var id int64
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+		rank, err := data.endRank(ctx, tx, projectID(task))
+		if err != nil {
+			return err
+		}
+		id, err = data.dialect.insert(ctx, tx, insertQuery, task.Date, task.Title, task.Description, task.Status, task.Repeat,
+			now, now, nullTime(task.CompletedAt), priority(task), task.Time, projectID(task), parentID(task), rank,
+			OwnerFrom(ctx))
+		if err != nil {
+			return err
+		}
+		if err := data.setTaskTags(ctx, tx, id, task.Tags); err != nil {
+			return err
+		}
+		created, err := data.getAnyTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := data.touchAncestors(ctx, tx, nil, created); err != nil {
+			return err
+		}
+		if err := data.addEvent(ctx, tx, id, model.ActionCreate, nil, created); err != nil {
+			return err
+		}
+		return data.addRevision(ctx, tx, model.ActionCreate, created)
+	})
 	if err != nil {
 		return 0, err
 	}
-	defer stmt.Close()
+	return id, nil
+}
 
-	res, err := stmt.Exec(task.Date, task.Title, task.Description)
-	if err != nil {
-		return 0, err
+// priority возвращает приоритет задачи для записи в базу данных, 0 означает приоритет по умолчанию
+func priority(task model.Task) int {
+	if task.Priority == 0 {
+		return model.PriorityLowest
 	}
+	return task.Priority
+}
 
-	lastID, err := res.LastInsertId()
-	if err != nil {
-		return 0, err
+// projectID возвращает проект задачи для записи в базу данных, 0 означает Входящие
+func projectID(task model.Task) int64 {
+	if task.ProjectId == 0 {
+		return model.InboxProjectId
 	}
+	return task.ProjectId
+}
+
+// parentID возвращает родителя задачи для записи в базу данных, nil и 0 означают задачу верхнего уровня
+func parentID(task model.Task) sql.NullInt64 {
+	if task.ParentId == nil || *task.ParentId == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *task.ParentId, Valid: true}
+}
 
-	return lastID, nil
+// scanner объединяет *sql.Row и *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// taskRow принимает значения столбцов taskColumns, допускающих NULL
+type taskRow struct {
+	task                              model.Task
+	status, repeat                    sql.NullString
+	createdAt, updatedAt, completedAt sql.NullTime
+	deletedAt                         sql.NullTime
+	parentID                          sql.NullInt64
+}
+
+// dest возвращает приемники Scan в порядке taskColumns
+func (r *taskRow) dest() []any {
+	return []any{&r.task.Id, &r.task.Date, &r.task.Title, &r.task.Description, &r.status, &r.repeat,
+		&r.createdAt, &r.updatedAt, &r.completedAt, &r.task.Version, &r.deletedAt, &r.task.Priority, &r.task.Time,
+		&r.task.ProjectId, &r.task.SortRank, &r.parentID, &r.task.OwnerId}
+}
+
+// result возвращает считанную задачу
+func (r *taskRow) result() model.Task {
+	r.task.Status = r.status.String
+	r.task.Repeat = r.repeat.String
+	r.task.CreatedAt = timePtr(r.createdAt)
+	r.task.UpdatedAt = timePtr(r.updatedAt)
+	r.task.CompletedAt = timePtr(r.completedAt)
+	r.task.DeletedAt = timePtr(r.deletedAt)
+	if r.parentID.Valid {
+		r.task.ParentId = &r.parentID.Int64
+	}
+	return r.task
+}
+
+// timePtr преобразует sql.NullTime в указатель на время в UTC
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+// nullTime преобразует указатель на время в значение для записи в базу данных
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// scanTask считывает задачу из строки результата в порядке taskColumns
+func scanTask(row scanner) (model.Task, error) {
+	var r taskRow
+	err := row.Scan(r.dest()...)
+	return r.result(), err
 }
 
 // getTasksByRows извлекает задачи из результата sql.Rows
-func getTasksByRows(rows *sql.Rows) ([]model.Task, error) {
+func getTasksByRows(rows *sql.Rows, scan func(row scanner) (model.Task, error)) ([]model.Task, error) {
+	defer rows.Close()
+
 	var tasks []model.Task
 	for rows.Next() {
-		var task model.Task
-		if err := rows.Scan(&task.Id, &task.Date, &task.Title, &task.Description); err != nil {
+		task, err := scan(rows)
+		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
@@ -96,113 +269,227 @@ func getTasksByRows(rows *sql.Rows) ([]model.Task, error) {
 	return tasks, nil
 }
 
-// GetTask получает задачу по ID
-func (data TaskData) GetTask(id int) (model.Task, error) {
-
-	row := data.db.QueryRow(getTaskQuery, id)
-
-	var task model.Task
-	err := row.Scan(&task.Id, &task.Date, &task.Title, &task.Description)
-	return task, err
-}
-
-// GetTasks получает все задачи с ограничением по количеству
-func (data TaskData) GetTasks(limit int) ([]model.Task, error) {
-
-	rows, err := data.db.Query(getTasksQuery, limit)
+// queryTasks выполняет запрос, возвращающий список задач
+func (data *TaskData) queryTasks(ctx context.Context, scan func(row scanner) (model.Task, error), query string, args ...any) ([]model.Task, error) {
+	rows, err := data.db.QueryContext(ctx, data.q(query), args...)
 	if err != nil {
 		return nil, err
 	}
-	return getTasksByRows(rows)
+	return getTasksByRows(rows, scan)
 }
 
-// GetTasksByDate получает задачи по дате с ограничением по количеству
-func (data TaskData) GetTasksByDate(date string, limit int) ([]model.Task, error) {
-
-	rows, err := data.db.Query(getTasksByDateQuery, date, limit)
+// GetTask получает задачу по ID
+func (data *TaskData) GetTask(ctx context.Context, id int64) (model.Task, error) {
+	task, err := scanTask(data.db.QueryRowContext(ctx, data.q(getTaskQuery), id, OwnerFrom(ctx)))
 	if err != nil {
-		return nil, err
+		return task, err
+	}
+	tasks := []model.Task{task}
+	if err := data.loadTags(ctx, data.db, tasks); err != nil {
+		return task, err
+	}
+	if err := data.loadProgress(ctx, data.db, tasks); err != nil {
+		return task, err
 	}
-	return getTasksByRows(rows)
+	err = data.loadBlockers(ctx, data.db, tasks)
+	return tasks[0], err
 }
 
-// GetTasksByStatus получает задачи по статусу с ограничением по количеству
-func (data TaskData) GetTasksByStatus(status string, limit int) ([]model.Task, error) {
-
-	rows, err := data.db.Query(getTasksByStatusQuery, status, limit)
+// FindTasks возвращает страницу задач, удовлетворяющих фильтру,
+// и курсор следующей страницы (пустой, если страница последняя)
+func (data *TaskData) FindTasks(ctx context.Context, filter model.TaskFilter) ([]model.Task, string, error) {
+	if len(filter.Sort) == 0 {
+		filter.Sort = DefaultSort
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = settings.TasksListRowsLimit
+	}
+	query, err := buildListQuery(data.dialect, filter, OwnerFrom(ctx))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return getTasksByRows(rows)
-}
-
-// GetTasksByDateAndStatus получает задачи по статусу и дате с ограничением по количеству
-func (data TaskData) GetTasksByDateAndStatus(status string, date string, limit int) ([]model.Task, error) {
-	rows, err := data.db.Query(getTasksByStatusAndDateQuery, status, date, limit)
+	tasks, err := data.queryTasks(ctx, query.scan, query.sql, query.args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return getTasksByRows(rows)
+	if err := data.loadTags(ctx, data.db, tasks); err != nil {
+		return nil, "", err
+	}
+	if err := data.loadProgress(ctx, data.db, tasks); err != nil {
+		return nil, "", err
+	}
+	if err := data.loadBlockers(ctx, data.db, tasks); err != nil {
+		return nil, "", err
+	}
+	if len(tasks) <= filter.Limit {
+		return tasks, "", nil
+	}
+	tasks = tasks[:filter.Limit]
+	return tasks, encodeCursor(filter.Sort, filter.Desc, query.keys, tasks[len(tasks)-1]), nil
 }
 
-// UpdateTask обновляет задачу в базе данных.
-func (data TaskData) UpdateTask(task model.Task) (bool, error) {
+// UpdateTask обновляет задачу в базе данных, увеличивает ее версию и записывает
+// изменение в журнал с действием action. Теги заменяются, только если task.Tags не nil,
+// а проект - только если указан task.ProjectId; перенесенная задача становится последней в новом проекте,
+// а ее подзадачи переносятся вместе с ней.
+// Задача обновляется, только если ее версия в базе данных равна task.Version,
+// поэтому false означает, что задача удалена или уже изменена другим запросом.
+func (data *TaskData) UpdateTask(ctx context.Context, task model.Task, action string) (bool, error) {
+	return data.change(ctx, task.Id, action, func(tx *sql.Tx) (bool, error) {
+		// Ранг нужен, только если задачу переносят в другой проект
+		var rank string
+		if task.ProjectId != 0 {
+			var err error
+			if rank, err = data.endRank(ctx, tx, task.ProjectId); err != nil {
+				return false, err
+			}
+		}
+		updated, err := data.execOne(ctx, updateQuery, task.Date, task.Title, task.Description, task.Status, task.Repeat,
+			time.Now().UTC(), nullTime(task.CompletedAt), priority(task), task.Time, parentID(task),
+			task.ProjectId, task.ProjectId, rank, task.Id, OwnerFrom(ctx), task.Version)(tx)
+		if err != nil || !updated {
+			return updated, err
+		}
+		if task.Tags != nil {
+			if err := data.setTaskTags(ctx, tx, task.Id, task.Tags); err != nil {
+				return false, err
+			}
+		}
+		return true, data.moveSubtasks(ctx, tx, task.Id)
+	})
+}
 
-	// Начало транзакции.
-	tx, err := data.db.Begin()
+// DeleteTask переносит задачу в корзину вместе с подзадачами, если ее версия равна version
+func (data *TaskData) DeleteTask(ctx context.Context, id int64, version int64) (bool, error) {
+	// Получаем задачу по ID для проверки существования
+	_, err := data.GetTask(ctx, id)
 	if err != nil {
 		return false, err
 	}
-	defer tx.Rollback() // Откат транзакции в случае ошибки.
+	now := time.Now().UTC()
+	return data.change(ctx, id, model.ActionDelete, func(tx *sql.Tx) (bool, error) {
+		deleted, err := data.execOne(ctx, deleteQuery, now, id, OwnerFrom(ctx), version)(tx)
+		if err != nil || !deleted {
+			return deleted, err
+		}
+		// Подзадачи попадают в корзину с тем же временем удаления, чтобы вернуться вместе с задачей
+		return true, data.cascadeSubtasks(ctx, tx, id, model.ActionDelete, func(subtaskID int64) func(tx *sql.Tx) (bool, error) {
+			return data.execOne(ctx, deleteSubtaskQuery, now, subtaskID, OwnerFrom(ctx))
+		})
+	})
+}
 
-	// Выполнение подготовленного запроса внутри транзакции.
-	result, err := tx.Exec(updateQuery, task.Date, task.Title, task.Description, task.Id)
-	if err != nil {
-		return false, err
-	}
+// RestoreTask возвращает задачу из корзины вместе с подзадачами, удаленными одновременно с ней.
+// false означает, что в корзине нет задачи с таким ID. Подзадачу, родительская задача которой
+// в корзине, вернуть нельзя: возвращается ошибка taskerror.ErrParentInTrash.
+func (data *TaskData) RestoreTask(ctx context.Context, id int64) (bool, error) {
+	now := time.Now().UTC()
+	return data.change(ctx, id, model.ActionRestore, func(tx *sql.Tx) (bool, error) {
+		task, err := data.getAnyTask(ctx, tx, id)
+		if err != nil || task == nil || task.DeletedAt == nil {
+			return false, err
+		}
+		if task.ParentId != nil {
+			// Подзадачу нельзя вернуть, пока ее родительская задача в корзине
+			parent, err := data.getAnyTask(ctx, tx, *task.ParentId)
+			if err != nil {
+				return false, err
+			}
+			if parent != nil && parent.DeletedAt != nil {
+				return false, taskerror.ErrParentInTrash
+			}
+		}
+		if restored, err := data.execOne(ctx, restoreQuery, now, id, OwnerFrom(ctx))(tx); err != nil || !restored {
+			return restored, err
+		}
+		return true, data.cascadeSubtasks(ctx, tx, id, model.ActionRestore, func(subtaskID int64) func(tx *sql.Tx) (bool, error) {
+			return data.execOne(ctx, restoreSubtaskQuery, now, subtaskID, OwnerFrom(ctx), *task.DeletedAt)
+		})
+	})
+}
 
-	// Получение количества обновленных строк.
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return false, err
-	}
+// PurgeTask окончательно удаляет задачу из корзины вместе с подзадачами
+func (data *TaskData) PurgeTask(ctx context.Context, id int64) (bool, error) {
+	return data.change(ctx, id, model.ActionPurge, data.purgeTree(ctx, id))
+}
 
-	// Коммит транзакции, если все операции без ошибок.
-	if err = tx.Commit(); err != nil {
-		return false, err
+// purgeTree возвращает изменение, окончательно удаляющее задачу из корзины вместе с подзадачами.
+// Подзадачи удаляются первыми, от самых глубоких, чтобы каждая попала в журнал.
+func (data *TaskData) purgeTree(ctx context.Context, id int64) func(tx *sql.Tx) (bool, error) {
+	return func(tx *sql.Tx) (bool, error) {
+		task, err := data.getAnyTask(ctx, tx, id)
+		if err != nil || task == nil || task.DeletedAt == nil {
+			return false, err
+		}
+		err = data.cascadeSubtasks(ctx, tx, id, model.ActionPurge, func(subtaskID int64) func(tx *sql.Tx) (bool, error) {
+			return data.execOne(ctx, purgeQuery, subtaskID, OwnerFrom(ctx))
+		})
+		if err != nil {
+			return false, err
+		}
+		return data.execOne(ctx, purgeQuery, id, OwnerFrom(ctx))(tx)
 	}
-
-	// Проверка, что была обновлена одна строка.
-	return rowsAffected == 1, nil
 }
 
-// DeleteTask удаляет задачу из базы данных по ID
-func (data TaskData) DeleteTask(id int) (bool, error) {
-	// Получаем задачу по ID для проверки существования
-	_, err := data.GetTask(id)
-	if err != nil {
-		return false, err
+// PurgeTrash окончательно удаляет задачи, перенесенные в корзину раньше before,
+// и возвращает их количество. В контексте WithAllOwners очищаются корзины всех пользователей,
+// в том числе задачи, которые владелец оставил в проекте, покинув его.
+func (data *TaskData) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	filter, args := "", []any{before.UTC()}
+	if !isAllOwners(ctx) {
+		// Задачи пользователя в проекте, из которого его исключили, ему больше не доступны
+		filter, args = " AND owner_id = ? AND "+visibleTask, append(args, OwnerFrom(ctx), OwnerFrom(ctx))
 	}
-
-	res, err := data.db.Exec(deleteQuery, sql.Named("id", id))
+	var purged int64
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		count := func() (int64, error) {
+			var n int64
+			err := tx.QueryRowContext(ctx, data.q(trashCountQuery+filter), args...).Scan(&n)
+			return n, err
+		}
+		trashed, err := count()
+		if err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, data.q(trashBeforeQuery+filter), args...)
+		if err != nil {
+			return err
+		}
+		tasks, err := getTasksByRows(rows, scanTask)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			// Подзадачи удаляются вместе с родительской задачей, и тогда повторно не изменяются.
+			// Задача удаляется от имени пользователя, которому она доступна, а запись попадает в журнал ее владельца.
+			purger := task.OwnerId
+			if err := tx.QueryRowContext(ctx, data.q(purgerQuery), task.OwnerId, task.ProjectId).Scan(&purger); err != nil {
+				return err
+			}
+			purgerCtx := WithOwner(ctx, purger)
+			if _, err := data.changeInTx(purgerCtx, tx, task.Id, model.ActionPurge, data.purgeTree(purgerCtx, task.Id)); err != nil {
+				return err
+			}
+		}
+		// Подзадачи задачи из корзины удалены не позже нее, поэтому считаются только задачи из корзины
+		left, err := count()
+		purged = trashed - left
+		return err
+	})
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-
-	deleted, err := res.RowsAffected()
-	return deleted == 1, err
+	return purged, nil
 }
 
 // openDb открывает соединение с базой данных
-func openDb(dataSourceName string) (*sql.DB, error) {
-	db, err := sql.Open(driverName, dataSourceName)
+func openDb(d dialect, dataSourceName string) (*sql.DB, error) {
+	db, err := sql.Open(d.driverName(), dataSourceName)
 	if err != nil {
 		return nil, err
 	}
-	if _, err := db.Exec(tableSchema); err != nil {
-		return nil, err
-	}
-	if _, err := db.Exec(indexSchema); err != nil {
+	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 	return db, nil
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	// dependencyCycleQuery проверяет, заблокирована ли задача из первого плейсхолдера, прямо или через другие задачи,
+	// задачей из второго плейсхолдера. Учитываются и задачи из корзины: после восстановления их связи возвращаются.
+	// PostgreSQL считает плейсхолдер без типа текстом, а тип столбца рекурсивного запроса должен совпадать
+	// в обеих частях UNION, поэтому плейсхолдеры приводятся к BIGINT явно.
+	dependencyCycleQuery = `
+WITH RECURSIVE blocked (id) AS (
+    SELECT CAST(? AS BIGINT)
+    UNION
+    SELECT task_dependencies.task_id FROM task_dependencies JOIN blocked ON task_dependencies.blocker_id = blocked.id
+)
+SELECT COUNT(*) FROM blocked WHERE id = CAST(? AS BIGINT)
+`
+	// insertDependencyQuery добавляет зависимость, только если обе задачи доступны пользователю из последних плейсхолдеров
+	insertDependencyQuery = `
+INSERT INTO task_dependencies (blocker_id, task_id, created_at)
+SELECT blockers.id, todolist.id, ? FROM todolist AS blockers, todolist
+WHERE blockers.id = ? AND todolist.id = ?
+    AND blockers.id IN (SELECT task_id FROM task_access WHERE user_id = ?)
+    AND todolist.id IN (SELECT task_id FROM task_access WHERE user_id = ?)
+ON CONFLICT (blocker_id, task_id) DO NOTHING
+`
+	deleteDependencyQuery = `
+DELETE FROM task_dependencies
+WHERE blocker_id = ? AND task_id = ? AND task_id IN (SELECT task_id FROM task_access WHERE user_id = ?)
+`
+	// touchTaskQuery увеличивает версию задачи, у которой изменились вычисляемые поля, чтобы изменился ее ETag
+	touchTaskQuery = "UPDATE todolist SET version = version + 1 WHERE id = ?"
+	// touchBlockedQuery увеличивает версии задач, которые блокирует задача из плейсхолдера
+	touchBlockedQuery = "UPDATE todolist SET version = version + 1 WHERE id IN (SELECT task_id FROM task_dependencies WHERE blocker_id = ?)"
+
+	blockedByQuery = "SELECT " + taskColumns + ` FROM todolist
+WHERE id IN (SELECT blocker_id FROM task_dependencies WHERE task_id = ?) AND ` + visibleTask + ` AND deleted_at IS NULL ORDER BY id`
+	blocksQuery = "SELECT " + taskColumns + ` FROM todolist
+WHERE id IN (SELECT task_id FROM task_dependencies WHERE blocker_id = ?) AND ` + visibleTask + ` AND deleted_at IS NULL ORDER BY id`
+
+	// blockersQuery выбирает невыполненные блокирующие задачи, доступные пользователю, для нескольких задач сразу
+	blockersQuery = `
+SELECT task_dependencies.task_id, task_dependencies.blocker_id FROM task_dependencies
+JOIN todolist AS blockers ON blockers.id = task_dependencies.blocker_id
+WHERE task_dependencies.task_id IN (%s) AND ` + openBlocker + `
+    AND blockers.id IN (SELECT task_id FROM task_access WHERE user_id = ?)
+ORDER BY task_dependencies.task_id, task_dependencies.blocker_id
+`
+	// openBlocker отбирает блокирующие задачи, которые еще не выполнены, не отменены и не в корзине
+	openBlocker = "blockers.deleted_at IS NULL AND COALESCE(blockers.status, '') NOT IN (?, ?)"
+
+	// blockedTasksCond отбирает задачи, у которых есть невыполненные блокирующие задачи
+	blockedTasksCond = `id IN (SELECT task_dependencies.task_id FROM task_dependencies
+    JOIN todolist AS blockers ON blockers.id = task_dependencies.blocker_id WHERE ` + openBlocker + ")"
+)
+
+// DependencyStore описывает хранилище зависимостей между задачами
+type DependencyStore interface {
+	AddDependency(ctx context.Context, blockerID, taskID int64) (bool, error)
+	RemoveDependency(ctx context.Context, blockerID, taskID int64) (bool, error)
+	FindDependencies(ctx context.Context, id int64) (model.TaskDependencies, error)
+}
+
+// AddDependency добавляет зависимость: задача blockerID блокирует задачу taskID.
+// Зависимость, которая замкнула бы цикл, не добавляется, и возвращается ErrDependencyCycle.
+// false означает, что такая зависимость уже есть или одной из задач нет.
+func (data *TaskData) AddDependency(ctx context.Context, blockerID, taskID int64) (bool, error) {
+	added := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		var cycle int
+		if err := tx.QueryRowContext(ctx, data.q(dependencyCycleQuery), taskID, blockerID).Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle > 0 {
+			return taskerror.ErrDependencyCycle
+		}
+		res, err := tx.ExecContext(ctx, data.q(insertDependencyQuery), time.Now().UTC(), blockerID, taskID,
+			OwnerFrom(ctx), OwnerFrom(ctx))
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil || affected != 1 {
+			return err
+		}
+		added = true
+		// У заблокированной задачи меняется blocked_by, поэтому меняется и ее версия
+		_, err = tx.ExecContext(ctx, data.q(touchTaskQuery), taskID)
+		return err
+	})
+	return added && err == nil, err
+}
+
+// RemoveDependency удаляет зависимость. false означает, что такой зависимости нет.
+func (data *TaskData) RemoveDependency(ctx context.Context, blockerID, taskID int64) (bool, error) {
+	removed := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, data.q(deleteDependencyQuery), blockerID, taskID, OwnerFrom(ctx))
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil || affected != 1 {
+			return err
+		}
+		removed = true
+		_, err = tx.ExecContext(ctx, data.q(touchTaskQuery), taskID)
+		return err
+	})
+	return removed && err == nil, err
+}
+
+// touchBlocked увеличивает версии задач, которые блокирует задача, если она выполнена, отменена или перенесена
+// в корзину либо вернулась к работе: у заблокированных задач меняется blocked_by
+func (data *TaskData) touchBlocked(ctx context.Context, tx *sql.Tx, before, after *model.Task) error {
+	if openTask(before) == openTask(after) {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, data.q(touchBlockedQuery), before.Id)
+	return err
+}
+
+// openTask проверяет, блокирует ли задача другие задачи: она не выполнена, не отменена и не в корзине, см. openBlocker
+func openTask(task *model.Task) bool {
+	return task != nil && task.DeletedAt == nil && task.Status != model.StatusDone && task.Status != model.StatusCancelled
+}
+
+// FindDependencies возвращает задачи, которые блокируют задачу id, и задачи, которые она блокирует,
+// кроме задач из корзины
+func (data *TaskData) FindDependencies(ctx context.Context, id int64) (model.TaskDependencies, error) {
+	var (
+		deps model.TaskDependencies
+		err  error
+	)
+	if deps.BlockedBy, err = data.queryTasks(ctx, scanTask, blockedByQuery, id, OwnerFrom(ctx)); err != nil {
+		return deps, err
+	}
+	if deps.Blocks, err = data.queryTasks(ctx, scanTask, blocksQuery, id, OwnerFrom(ctx)); err != nil {
+		return deps, err
+	}
+	for _, tasks := range [][]model.Task{deps.BlockedBy, deps.Blocks} {
+		if err := data.loadTags(ctx, data.db, tasks); err != nil {
+			return deps, err
+		}
+		if err := data.loadBlockers(ctx, data.db, tasks); err != nil {
+			return deps, err
+		}
+	}
+	return deps, nil
+}
+
+// loadBlockers заполняет у задач ID невыполненных блокирующих задач
+func (data *TaskData) loadBlockers(ctx context.Context, q queryer, tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(tasks))
+	args := make([]any, 0, len(tasks)+2)
+	for i, task := range tasks {
+		index[task.Id] = i
+		args = append(args, task.Id)
+	}
+	args = append(args, model.StatusDone, model.StatusCancelled, OwnerFrom(ctx))
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tasks)), ", ")
+	rows, err := q.QueryContext(ctx, data.q(strings.Replace(blockersQuery, "%s", placeholders, 1)), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, blockerID int64
+		if err := rows.Scan(&taskID, &blockerID); err != nil {
+			return err
+		}
+		i := index[taskID]
+		tasks[i].BlockedBy = append(tasks[i].BlockedBy, blockerID)
+	}
+	return rows.Err()
+}
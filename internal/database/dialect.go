@@ -0,0 +1,24 @@
+package database
+
+import "context"
+
+// dialect скрывает различия между поддерживаемыми СУБД
+type dialect interface {
+	// name возвращает имя диалекта, совпадающее с каталогом миграций
+	name() string
+	// driverName возвращает имя драйвера database/sql
+	driverName() string
+	// rebind переводит плейсхолдеры "?" в синтаксис СУБД
+	rebind(query string) string
+	// ilike возвращает оператор сравнения с шаблоном без учета регистра
+	ilike() string
+	// searchSource возвращает подзапрос полнотекстового поиска по задачам
+	// со столбцами todolist и searchColumns и одним плейсхолдером для поискового выражения
+	searchSource() string
+	// searchExpr строит поисковое выражение СУБД из разобранного запроса
+	searchExpr(terms []searchTerm) string
+	// insert выполняет INSERT и возвращает идентификатор новой строки
+	insert(ctx context.Context, q queryer, query string, args ...any) (int64, error)
+	// uniqueViolation проверяет, отклонена ли запись из-за ограничения UNIQUE или PRIMARY KEY
+	uniqueViolation(err error) bool
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+const (
+	insertEventQuery = "INSERT INTO task_events (task_id, action, actor, changes, created_at, owner_id) VALUES (?, ?, ?, ?, ?, ?)"
+	eventColumns     = "id, task_id, action, actor, changes, created_at"
+
+	// getAnyTaskQuery читает задачу независимо от того, находится ли она в корзине
+	getAnyTaskQuery = "SELECT " + taskColumns + " FROM todolist WHERE id = ? AND " + visibleTask
+
+	// eventsCursorSort отмечает курсоры журнала, чтобы их нельзя было перепутать с курсорами списка задач
+	eventsCursorSort = "events"
+)
+
+// actorKey ключ контекста, под которым хранится инициатор изменений
+type actorKey struct{}
+
+// WithActor возвращает контекст, изменения в котором записываются в журнал от имени actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom возвращает инициатора изменений из контекста
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// eventFields возвращает поля задачи, изменения которых записываются в журнал.
+// Пустые значения записываются как null, nil означает отсутствующую задачу.
+func eventFields(task *model.Task) map[string]any {
+	fields := map[string]any{}
+	if task == nil {
+		return fields
+	}
+	text := func(name, value string) {
+		if len(value) > 0 {
+			fields[name] = value
+		}
+	}
+	timestamp := func(name string, value *time.Time) {
+		if value != nil {
+			fields[name] = value.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	text("title", task.Title)
+	text("description", task.Description)
+	text("date", task.Date)
+	text("time", task.Time)
+	text("status", task.Status)
+	text("repeat", task.Repeat)
+	if task.ProjectId > 0 {
+		fields["project_id"] = task.ProjectId
+	}
+	if task.ParentId != nil {
+		fields["parent_id"] = *task.ParentId
+	}
+	if task.Priority > 0 {
+		fields["priority"] = task.Priority
+	}
+	if len(task.Tags) > 0 {
+		fields["tags"] = task.Tags
+	}
+	timestamp("completed_at", task.CompletedAt)
+	timestamp("deleted_at", task.DeletedAt)
+	return fields
+}
+
+// DiffTask возвращает поля задачи, значения которых различаются в before и after.
+// nil вместо задачи означает ее отсутствие, тогда в результат попадают все непустые поля другой задачи.
+func DiffTask(before, after *model.Task) map[string]model.FieldChange {
+	from, to := eventFields(before), eventFields(after)
+	changes := map[string]model.FieldChange{}
+	for name := range from {
+		if !reflect.DeepEqual(from[name], to[name]) {
+			changes[name] = model.FieldChange{From: from[name], To: to[name]}
+		}
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			changes[name] = model.FieldChange{From: nil, To: to[name]}
+		}
+	}
+	return changes
+}
+
+// addEvent записывает в журнал изменение задачи от состояния before к состоянию after.
+// Запись попадает в журнал владельца задачи, даже если задачу изменил участник ее проекта.
+func (data *TaskData) addEvent(ctx context.Context, q queryer, taskID int64, action string, before, after *model.Task) error {
+	changes, err := json.Marshal(DiffTask(before, after))
+	if err != nil {
+		return err
+	}
+	owner := OwnerFrom(ctx)
+	if after != nil {
+		owner = after.OwnerId
+	} else if before != nil {
+		owner = before.OwnerId
+	}
+	_, err = q.ExecContext(ctx, data.q(insertEventQuery), taskID, action, actorFrom(ctx), string(changes), time.Now().UTC(),
+		owner)
+	return err
+}
+
+// withTx выполняет fn в транзакции и фиксирует ее, если fn завершилась без ошибки
+func (data *TaskData) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getAnyTask читает задачу, в том числе из корзины. nil означает, что задачи нет.
+func (data *TaskData) getAnyTask(ctx context.Context, q queryer, id int64) (*model.Task, error) {
+	task, err := scanTask(q.QueryRowContext(ctx, data.q(getAnyTaskQuery), id, OwnerFrom(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tasks := []model.Task{task}
+	if err := data.loadTags(ctx, q, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// execOne возвращает изменение, выполняющее запрос, который должен изменить ровно одну строку
+func (data *TaskData) execOne(ctx context.Context, query string, args ...any) func(tx *sql.Tx) (bool, error) {
+	return func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, data.q(query), args...)
+		if err != nil {
+			return false, err
+		}
+		affected, err := res.RowsAffected()
+		return affected == 1, err
+	}
+}
+
+// change выполняет в транзакции изменение одной задачи, записывает его в журнал
+// и сохраняет снимок задачи (снимки окончательно удаленной задачи удаляются).
+// Версии задач, вычисляемые поля которых зависят от изменения, тоже увеличиваются.
+// false означает, что update не изменил задачу, и тогда ничего не записывается.
+func (data *TaskData) change(ctx context.Context, id int64, action string, update func(tx *sql.Tx) (bool, error)) (bool, error) {
+	changed := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		changed, err = data.changeInTx(ctx, tx, id, action, update)
+		return err
+	})
+	return changed && err == nil, err
+}
+
+// changeInTx выполняет изменение задачи, как change, в уже открытой транзакции tx
+func (data *TaskData) changeInTx(ctx context.Context, tx *sql.Tx, id int64, action string, update func(tx *sql.Tx) (bool, error)) (bool, error) {
+	before, err := data.getAnyTask(ctx, tx, id)
+	if err != nil || before == nil {
+		return false, err
+	}
+	if updated, err := update(tx); err != nil || !updated {
+		return false, err
+	}
+	after, err := data.getAnyTask(ctx, tx, id)
+	if err != nil {
+		return false, err
+	}
+	if err := data.touchBlocked(ctx, tx, before, after); err != nil {
+		return false, err
+	}
+	if err := data.touchAncestors(ctx, tx, before, after); err != nil {
+		return false, err
+	}
+	if err := data.addEvent(ctx, tx, id, action, before, after); err != nil {
+		return false, err
+	}
+	if after == nil {
+		return true, data.deleteRevisions(ctx, tx, id)
+	}
+	return true, data.addRevision(ctx, tx, action, after)
+}
+
+// FindEvents возвращает страницу журнала изменений задач пользователя и доступных ему задач проектов
+// от новых записей к старым и курсор следующей страницы (пустой, если страница последняя)
+func (data *TaskData) FindEvents(ctx context.Context, filter model.EventFilter) ([]model.TaskEvent, string, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = settings.TasksListRowsLimit
+	}
+
+	var query taskQuery
+	owner := OwnerFrom(ctx)
+	// История окончательно удаленных задач видна их владельцу, а история остальных - тем, кому доступны задачи
+	query.add("(task_id IN (SELECT task_id FROM task_access WHERE user_id = ?) OR (owner_id = ? AND task_id NOT IN (SELECT id FROM todolist)))",
+		owner, owner)
+	if filter.TaskId > 0 {
+		query.add("task_id = ?", filter.TaskId)
+	}
+	if len(filter.Actions) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.Actions)), ", ")
+		args := make([]any, len(filter.Actions))
+		for i, action := range filter.Actions {
+			args[i] = action
+		}
+		query.add("action IN ("+placeholders+")", args...)
+	}
+	if !filter.From.IsZero() {
+		query.add("created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query.add("created_at < ?", filter.To.UTC())
+	}
+	if len(filter.Cursor) > 0 {
+		values, err := decodeCursor(filter.Cursor, eventsCursorSort, true, []sortKey{idKey})
+		if err != nil {
+			return nil, "", err
+		}
+		query.add("id < ?", values[0])
+	}
+
+	sqlQuery := "SELECT " + eventColumns + " FROM task_events WHERE " + strings.Join(query.where, " AND ")
+	sqlQuery += " ORDER BY id DESC LIMIT ?"
+	query.args = append(query.args, filter.Limit+1)
+
+	rows, err := data.db.QueryContext(ctx, data.q(sqlQuery), query.args...)
+	if err != nil {
+		return nil, "", err
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(events) <= filter.Limit {
+		return events, "", nil
+	}
+	events = events[:filter.Limit]
+	return events, encodeEventCursor(events[len(events)-1]), nil
+}
+
+// scanEvents извлекает записи журнала из результата sql.Rows
+func scanEvents(rows *sql.Rows) ([]model.TaskEvent, error) {
+	defer rows.Close()
+
+	events := []model.TaskEvent{}
+	for rows.Next() {
+		var (
+			event   model.TaskEvent
+			changes []byte
+		)
+		if err := rows.Scan(&event.Id, &event.TaskId, &event.Action, &event.Actor, &changes, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &event.Changes); err != nil {
+			return nil, err
+		}
+		event.CreatedAt = event.CreatedAt.UTC()
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// encodeEventCursor кодирует позицию после записи журнала last
+func encodeEventCursor(last model.TaskEvent) string {
+	data, _ := json.Marshal(cursor{Sort: eventsCursorSort, Desc: true, Values: []any{last.Id}})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
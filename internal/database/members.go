@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	// taskAccessQuery выбирает владельца доступной пользователю задачи, в том числе из корзины,
+	// и роль пользователя в ее проекте. Первый и последний плейсхолдеры - пользователь.
+	taskAccessQuery = `
+SELECT todolist.owner_id, COALESCE(project_access.role, '') FROM todolist
+LEFT JOIN project_access ON project_access.project_id = todolist.project_id AND project_access.user_id = ?
+WHERE todolist.id = ? AND todolist.` + visibleTask
+
+	// membersQuery выбирает владельца и участников проекта: владелец считается участником с момента создания проекта
+	membersQuery = `
+SELECT users.id, users.login, users.name, 'owner', projects.created_at FROM projects
+JOIN users ON users.id = projects.owner_id
+WHERE projects.id = ?
+UNION ALL
+SELECT users.id, users.login, users.name, project_members.role, project_members.created_at FROM project_members
+JOIN users ON users.id = project_members.user_id
+WHERE project_members.project_id = ?
+ORDER BY 5, 1
+`
+	// Участниками управляют в проектах, в которых у пользователя из последнего плейсхолдера есть роль
+	updateMemberQuery = "UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ? AND " +
+		"project_id IN (SELECT project_id FROM project_access WHERE user_id = ?)"
+	deleteMemberQuery = "DELETE FROM project_members WHERE project_id = ? AND user_id = ? AND " +
+		"project_id IN (SELECT project_id FROM project_access WHERE user_id = ?)"
+
+	insertInvitationQuery = `
+INSERT INTO project_invitations (project_id, user_id, role, invited_by, created_at) VALUES (?, ?, ?, ?, ?)
+`
+	invitationsQuery = `
+SELECT project_invitations.id, project_invitations.project_id, projects.name, project_invitations.user_id,
+    invitees.login, project_invitations.role, inviters.login, project_invitations.created_at
+FROM project_invitations
+JOIN projects ON projects.id = project_invitations.project_id
+JOIN users AS invitees ON invitees.id = project_invitations.user_id
+JOIN users AS inviters ON inviters.id = project_invitations.invited_by
+`
+	// visibleInvitation отбирает приглашения, которые видит пользователь из обоих плейсхолдеров:
+	// адресованные ему и приглашения в проекты, в которых у него есть роль
+	visibleInvitation = "(project_invitations.user_id = ? OR " +
+		"project_invitations.project_id IN (SELECT project_id FROM project_access WHERE user_id = ?))"
+	deleteInvitationQuery = "DELETE FROM project_invitations WHERE id = ? AND " + visibleInvitation
+
+	// acceptInvitationQuery делает пользователя участником проекта по адресованному ему приглашению
+	acceptInvitationQuery = `
+INSERT INTO project_members (project_id, user_id, role, created_at)
+SELECT project_id, user_id, role, ? FROM project_invitations WHERE id = ? AND user_id = ?
+`
+	deleteAcceptedQuery = "DELETE FROM project_invitations WHERE id = ?"
+
+	// invitedMemberQuery проверяет, есть ли уже роль в проекте у пользователя, которому адресовано приглашение,
+	// в том числе роль владельца
+	invitedMemberQuery = `
+SELECT COUNT(*) FROM project_invitations
+JOIN project_access ON project_access.project_id = project_invitations.project_id
+    AND project_access.user_id = project_invitations.user_id
+WHERE project_invitations.id = ? AND project_invitations.user_id = ?
+`
+)
+
+// MemberStore описывает хранилище участников проектов и приглашений в проекты
+type MemberStore interface {
+	TaskAccess(ctx context.Context, id int64) (ownerID int64, role string, err error)
+	FindMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error)
+	UpdateMember(ctx context.Context, projectID int64, member model.ProjectMember) (bool, error)
+	DeleteMember(ctx context.Context, projectID, userID int64) (bool, error)
+	InsertInvitation(ctx context.Context, invitation model.Invitation) (int64, error)
+	GetInvitation(ctx context.Context, id int64) (model.Invitation, error)
+	FindInvitations(ctx context.Context, projectID int64) ([]model.Invitation, error)
+	DeleteInvitation(ctx context.Context, id int64) (bool, error)
+	AcceptInvitation(ctx context.Context, id int64) (bool, error)
+}
+
+// TaskAccess возвращает владельца задачи id, в том числе из корзины, и роль пользователя в ее проекте,
+// пустую, если роли нет. sql.ErrNoRows означает, что задачи нет или она недоступна пользователю.
+func (data *TaskData) TaskAccess(ctx context.Context, id int64) (int64, string, error) {
+	var (
+		ownerID int64
+		role    string
+	)
+	owner := OwnerFrom(ctx)
+	err := data.db.QueryRowContext(ctx, data.q(taskAccessQuery), owner, id, owner).Scan(&ownerID, &role)
+	return ownerID, role, err
+}
+
+// FindMembers возвращает владельца и участников проекта в порядке вступления
+func (data *TaskData) FindMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error) {
+	rows, err := data.db.QueryContext(ctx, data.q(membersQuery), projectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []model.ProjectMember{}
+	for rows.Next() {
+		var (
+			member    model.ProjectMember
+			createdAt sql.NullTime
+		)
+		if err := rows.Scan(&member.UserId, &member.Login, &member.Name, &member.Role, &createdAt); err != nil {
+			return nil, err
+		}
+		member.CreatedAt = timePtr(createdAt)
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// UpdateMember изменяет роль участника проекта. false означает, что такого участника нет.
+func (data *TaskData) UpdateMember(ctx context.Context, projectID int64, member model.ProjectMember) (bool, error) {
+	res, err := data.db.ExecContext(ctx, data.q(updateMemberQuery), member.Role, projectID, member.UserId, OwnerFrom(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// DeleteMember исключает участника из проекта. false означает, что такого участника нет.
+func (data *TaskData) DeleteMember(ctx context.Context, projectID, userID int64) (bool, error) {
+	res, err := data.db.ExecContext(ctx, data.q(deleteMemberQuery), projectID, userID, OwnerFrom(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// InsertInvitation создает приглашение от имени пользователя и возвращает его ID
+func (data *TaskData) InsertInvitation(ctx context.Context, invitation model.Invitation) (int64, error) {
+	return data.dialect.insert(ctx, data.db, insertInvitationQuery, invitation.ProjectId, invitation.UserId,
+		invitation.Role, OwnerFrom(ctx), time.Now().UTC())
+}
+
+// GetInvitation возвращает приглашение по ID, если оно адресовано пользователю
+// или приглашает в проект, в котором у него есть роль
+func (data *TaskData) GetInvitation(ctx context.Context, id int64) (model.Invitation, error) {
+	owner := OwnerFrom(ctx)
+	query := invitationsQuery + "WHERE project_invitations.id = ? AND " + visibleInvitation
+	return scanInvitation(data.db.QueryRowContext(ctx, data.q(query), id, owner, owner))
+}
+
+// FindInvitations возвращает приглашения в проект projectID или, если projectID равен 0,
+// адресованные пользователю приглашения, от новых к старым
+func (data *TaskData) FindInvitations(ctx context.Context, projectID int64) ([]model.Invitation, error) {
+	owner := OwnerFrom(ctx)
+	query, args := invitationsQuery+"WHERE project_invitations.user_id = ?", []any{owner}
+	if projectID > 0 {
+		query, args = invitationsQuery+"WHERE project_invitations.project_id = ? AND "+visibleInvitation,
+			[]any{projectID, owner, owner}
+	}
+	rows, err := data.db.QueryContext(ctx, data.q(query+" ORDER BY project_invitations.id DESC"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []model.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteInvitation удаляет видимое пользователю приглашение. false означает, что приглашения нет.
+func (data *TaskData) DeleteInvitation(ctx context.Context, id int64) (bool, error) {
+	owner := OwnerFrom(ctx)
+	res, err := data.db.ExecContext(ctx, data.q(deleteInvitationQuery), id, owner, owner)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// AcceptInvitation принимает адресованное пользователю приглашение: пользователь становится участником проекта
+// с ролью из приглашения, а приглашение удаляется. false означает, что такого приглашения у пользователя нет.
+// Если у пользователя уже есть роль в проекте, приглашение остается, и возвращается ErrAlreadyMember.
+func (data *TaskData) AcceptInvitation(ctx context.Context, id int64) (bool, error) {
+	accepted := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		var member int
+		if err := tx.QueryRowContext(ctx, data.q(invitedMemberQuery), id, OwnerFrom(ctx)).Scan(&member); err != nil {
+			return err
+		}
+		if member > 0 {
+			return taskerror.ErrAlreadyMember
+		}
+		res, err := tx.ExecContext(ctx, data.q(acceptInvitationQuery), time.Now().UTC(), id, OwnerFrom(ctx))
+		if data.dialect.uniqueViolation(err) {
+			// Приглашение принято одновременно другим запросом
+			return taskerror.ErrAlreadyMember
+		}
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil || affected != 1 {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, data.q(deleteAcceptedQuery), id); err != nil {
+			return err
+		}
+		accepted = true
+		return nil
+	})
+	return accepted && err == nil, err
+}
+
+// scanInvitation считывает приглашение из строки результата invitationsQuery
+func scanInvitation(row scanner) (model.Invitation, error) {
+	var (
+		invitation model.Invitation
+		createdAt  sql.NullTime
+	)
+	err := row.Scan(&invitation.Id, &invitation.ProjectId, &invitation.ProjectName, &invitation.UserId,
+		&invitation.Login, &invitation.Role, &invitation.InvitedBy, &createdAt)
+	invitation.CreatedAt = timePtr(createdAt)
+	return invitation, err
+}
@@ -0,0 +1,269 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+const (
+	migrationsTableSchema = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)
+`
+	getAppliedMigrationsQuery = "SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version"
+	insertMigrationQuery      = "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"
+	deleteMigrationQuery      = "DELETE FROM schema_migrations WHERE version = ?"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrUnknownMigration = errors.New("database has migration unknown to this build")
+)
+
+// Migration представляет одну версию схемы базы данных
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationState описывает состояние миграции в конкретной базе данных
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// appliedMigration представляет строку таблицы schema_migrations
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// loadMigrations читает встроенные миграции диалекта и упорядочивает их по версии.
+// Имена файлов имеют вид NNNN_name.up.sql и NNNN_name.down.sql.
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+		num, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", file, err)
+		}
+		body, err := fs.ReadFile(migrationsFS, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(m.Up) == 0 {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrations возвращает миграции текущего диалекта
+func (data *TaskData) migrations() ([]Migration, error) {
+	return loadMigrations(path.Join("migrations", data.dialect.name()))
+}
+
+// appliedMigrations возвращает примененные миграции, создавая служебную таблицу при необходимости
+func (data *TaskData) appliedMigrations(ctx context.Context) (map[int64]appliedMigration, error) {
+	if _, err := data.db.ExecContext(ctx, migrationsTableSchema); err != nil {
+		return nil, err
+	}
+
+	rows, err := data.db.QueryContext(ctx, getAppliedMigrationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var m appliedMigration
+		if err := rows.Scan(&m.version, &m.name, &m.checksum, &m.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[m.version] = m
+	}
+	return applied, rows.Err()
+}
+
+// verifyMigrations сверяет примененные миграции со встроенными в сборку
+func verifyMigrations(migrations []Migration, applied map[int64]appliedMigration) error {
+	known := map[int64]Migration{}
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, a.name)
+		}
+		if m.Checksum != a.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, m.Name)
+		}
+	}
+	return nil
+}
+
+// Migrate применяет все непримененные миграции
+func (data *TaskData) Migrate(ctx context.Context) error {
+	_, err := data.MigrateUp(ctx, 0)
+	return err
+}
+
+// MigrateUp применяет до steps непримененных миграций (все, если steps <= 0)
+// и возвращает список примененных.
+func (data *TaskData) MigrateUp(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := data.migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := data.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyMigrations(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := data.runMigration(ctx, m.Up, insertMigrationQuery, m.Version, m.Name, m.Checksum, time.Now().UTC()); err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown откатывает steps последних примененных миграций (одну, если steps <= 0)
+// и возвращает список откаченных.
+func (data *TaskData) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	migrations, err := data.migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := data.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyMigrations(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if len(m.Down) == 0 {
+			return done, fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+		if err := data.runMigration(ctx, m.Down, deleteMigrationQuery, m.Version); err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrationStatus возвращает состояние всех известных миграций
+func (data *TaskData) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	migrations, err := data.migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := data.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Migration: m}
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.appliedAt
+			state.AppliedAt = &appliedAt
+		}
+		states = append(states, state)
+	}
+	return states, verifyMigrations(migrations, applied)
+}
+
+// runMigration выполняет скрипт миграции и запись в schema_migrations в одной транзакции
+func (data *TaskData) runMigration(ctx context.Context, script string, bookkeeping string, args ...any) error {
+	tx, err := data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, data.q(bookkeeping), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS indexdate;
+DROP TABLE IF EXISTS todolist;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS todolist_search_idx;
+DROP TRIGGER IF EXISTS todolist_search_update ON todolist;
+DROP FUNCTION IF EXISTS todolist_search_update();
+ALTER TABLE todolist DROP COLUMN IF EXISTS search;
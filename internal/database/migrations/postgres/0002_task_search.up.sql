@@ -0,0 +1,20 @@
+ALTER TABLE todolist ADD COLUMN IF NOT EXISTS search tsvector;
+
+CREATE OR REPLACE FUNCTION todolist_search_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search :=
+        setweight(to_tsvector('russian', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(NEW.description, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todolist_search_update
+    BEFORE INSERT OR UPDATE OF title, description ON todolist
+    FOR EACH ROW EXECUTE FUNCTION todolist_search_update();
+
+UPDATE todolist SET search =
+    setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('russian', COALESCE(description, '')), 'B');
+
+CREATE INDEX IF NOT EXISTS todolist_search_idx ON todolist USING GIN (search);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS todolist_status_idx;
+ALTER TABLE todolist DROP CONSTRAINT IF EXISTS todolist_status_check;
+ALTER TABLE todolist ALTER COLUMN status DROP NOT NULL;
+ALTER TABLE todolist ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE todolist DROP COLUMN completed_at;
+ALTER TABLE todolist DROP COLUMN updated_at;
+ALTER TABLE todolist DROP COLUMN created_at;
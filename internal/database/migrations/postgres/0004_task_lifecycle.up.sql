@@ -0,0 +1,18 @@
+ALTER TABLE todolist ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE todolist ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE todolist ADD COLUMN completed_at TIMESTAMPTZ;
+
+UPDATE todolist SET status = CASE
+    WHEN status IN ('done', 'Выполнено') THEN 'done'
+    WHEN status IN ('in_progress', 'blocked', 'cancelled') THEN status
+    ELSE 'todo'
+END;
+
+UPDATE todolist SET completed_at = now() WHERE status = 'done';
+
+ALTER TABLE todolist ALTER COLUMN status SET DEFAULT 'todo';
+ALTER TABLE todolist ALTER COLUMN status SET NOT NULL;
+ALTER TABLE todolist ADD CONSTRAINT todolist_status_check
+    CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS todolist_status_idx ON todolist (status);
@@ -0,0 +1 @@
+ALTER TABLE todolist DROP COLUMN version;
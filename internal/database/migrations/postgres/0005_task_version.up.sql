@@ -0,0 +1 @@
+ALTER TABLE todolist ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE todolist ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS todolist_deleted_at_idx ON todolist (deleted_at);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_idx ON task_events (task_id, id);
+CREATE INDEX IF NOT EXISTS task_events_created_at_idx ON task_events (created_at);
+
+-- Журнал только дополняется: записи нельзя изменить или удалить
+CREATE OR REPLACE FUNCTION task_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'task_events is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER task_events_append_only
+    BEFORE UPDATE OR DELETE ON task_events
+    FOR EACH ROW EXECUTE FUNCTION task_events_append_only();
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS task_revisions (
+    task_id BIGINT NOT NULL,
+    revision BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    date VARCHAR(8),
+    title TEXT,
+    description TEXT,
+    status TEXT,
+    repeat TEXT,
+    completed_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (task_id, revision)
+);
+
+-- Текущее состояние существующих задач становится их первым сохраненным снимком
+INSERT INTO task_revisions (task_id, revision, action, date, title, description, status, repeat, completed_at, deleted_at, created_at)
+SELECT id, version, CASE WHEN version = 1 THEN 'create' ELSE 'update' END,
+       date, title, description, status, repeat, completed_at, deleted_at, COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM todolist;
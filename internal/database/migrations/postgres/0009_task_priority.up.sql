@@ -0,0 +1,8 @@
+ALTER TABLE todolist ADD COLUMN priority SMALLINT NOT NULL DEFAULT 4;
+ALTER TABLE todolist ADD COLUMN due_time VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE todolist ADD CONSTRAINT todolist_priority_check CHECK (priority BETWEEN 1 AND 4);
+
+CREATE INDEX IF NOT EXISTS todolist_priority_idx ON todolist (priority, date, due_time);
+
+ALTER TABLE task_revisions ADD COLUMN priority SMALLINT NOT NULL DEFAULT 4;
+ALTER TABLE task_revisions ADD COLUMN due_time VARCHAR(5) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE task_revisions DROP COLUMN tags;
+
+DROP TABLE IF EXISTS task_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id BIGINT NOT NULL REFERENCES todolist (id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_tags_tag_idx ON task_tags (tag_id);
+
+ALTER TABLE task_revisions ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    archived_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Входящие: проект по умолчанию, в который попадают задачи без проекта
+INSERT INTO projects (id, name, created_at, updated_at) VALUES (1, 'Inbox', NOW(), NOW());
+SELECT setval(pg_get_serial_sequence('projects', 'id'), 1);
+
+ALTER TABLE todolist ADD COLUMN project_id BIGINT NOT NULL DEFAULT 1 REFERENCES projects (id);
+ALTER TABLE todolist ADD COLUMN position BIGINT NOT NULL DEFAULT 0;
+UPDATE todolist SET position = id;
+
+CREATE INDEX IF NOT EXISTS todolist_project_idx ON todolist (project_id, position);
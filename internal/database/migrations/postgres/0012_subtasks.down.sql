@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS todolist_parent_idx;
+
+ALTER TABLE todolist DROP COLUMN parent_id;
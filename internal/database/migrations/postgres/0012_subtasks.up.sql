@@ -0,0 +1,3 @@
+ALTER TABLE todolist ADD COLUMN parent_id BIGINT REFERENCES todolist (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS todolist_parent_idx ON todolist (parent_id);
@@ -0,0 +1,10 @@
+-- blocker_id блокирует task_id: task_id нельзя начинать, пока blocker_id не выполнена или не отменена
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    blocker_id BIGINT NOT NULL REFERENCES todolist (id) ON DELETE CASCADE,
+    task_id BIGINT NOT NULL REFERENCES todolist (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (blocker_id, task_id),
+    CHECK (blocker_id <> task_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_task_idx ON task_dependencies (task_id);
@@ -0,0 +1,11 @@
+ALTER TABLE todolist ADD COLUMN position BIGINT NOT NULL DEFAULT 0;
+UPDATE todolist SET position = (
+    SELECT COUNT(*) FROM todolist AS siblings
+    WHERE siblings.project_id = todolist.project_id
+      AND (siblings.sort_rank < todolist.sort_rank OR (siblings.sort_rank = todolist.sort_rank AND siblings.id <= todolist.id))
+);
+
+DROP INDEX IF EXISTS todolist_project_idx;
+ALTER TABLE todolist DROP COLUMN sort_rank;
+
+CREATE INDEX IF NOT EXISTS todolist_project_idx ON todolist (project_id, position);
@@ -0,0 +1,10 @@
+-- Порядок задач проекта задается строковым рангом: задачи упорядочены по sort_rank побайтово (COLLATE "C"),
+-- и для переноса задачи между двумя другими достаточно изменить ранг одной задачи.
+-- Ранг не может оканчиваться на 0, иначе между ним и соседним рангом может не найтись места.
+ALTER TABLE todolist ADD COLUMN sort_rank TEXT COLLATE "C" NOT NULL DEFAULT '';
+UPDATE todolist SET sort_rank = lpad(position::text, 9, '0') || 'i';
+
+DROP INDEX IF EXISTS todolist_project_idx;
+ALTER TABLE todolist DROP COLUMN position;
+
+CREATE INDEX IF NOT EXISTS todolist_project_idx ON todolist (project_id, sort_rank);
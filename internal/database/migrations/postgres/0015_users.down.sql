@@ -0,0 +1,22 @@
+-- Одноименные теги разных пользователей объединяются, а одноименные проекты получают в имени свой ID
+UPDATE task_tags SET tag_id = merged.id
+FROM tags, (SELECT MIN(id) AS id, name FROM tags GROUP BY name) AS merged
+WHERE tags.id = task_tags.tag_id AND merged.name = tags.name AND merged.id <> tags.id
+    AND NOT EXISTS (SELECT 1 FROM task_tags AS dup WHERE dup.task_id = task_tags.task_id AND dup.tag_id = merged.id);
+DELETE FROM tags WHERE id NOT IN (SELECT MIN(id) FROM tags GROUP BY name);
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_owner_name_key;
+ALTER TABLE tags DROP COLUMN owner_id;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
+
+UPDATE projects SET name = name || ' #' || id WHERE owner_id IS NOT NULL AND owner_id <> 1;
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_owner_name_key;
+ALTER TABLE projects DROP COLUMN owner_id;
+ALTER TABLE projects ADD CONSTRAINT projects_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS task_events_owner_idx;
+ALTER TABLE task_events DROP COLUMN owner_id;
+
+DROP INDEX IF EXISTS todolist_owner_idx;
+ALTER TABLE todolist DROP COLUMN owner_id;
+
+DROP TABLE IF EXISTS users;
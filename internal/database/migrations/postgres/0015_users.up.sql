@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    login TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Пользователь по умолчанию: ему принадлежат задачи, созданные до появления пользователей,
+-- от его имени работают запросы без входа и после входа по паролю TODO_PASSWORD.
+-- Пароля у него нет, поэтому войти под ним по логину нельзя.
+INSERT INTO users (id, login, created_at, updated_at) VALUES (1, 'default', NOW(), NOW());
+SELECT setval(pg_get_serial_sequence('users', 'id'), 1);
+
+ALTER TABLE todolist ADD COLUMN owner_id BIGINT NOT NULL DEFAULT 1 REFERENCES users (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS todolist_owner_idx ON todolist (owner_id, deleted_at);
+
+-- Журнал хранит владельца задачи, чтобы история окончательно удаленных задач оставалась видна только ему
+ALTER TABLE task_events ADD COLUMN owner_id BIGINT NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS task_events_owner_idx ON task_events (owner_id, id);
+
+-- Имена проектов и тегов уникальны в пределах владельца. Входящие общие для всех пользователей, у них нет владельца.
+ALTER TABLE projects ADD COLUMN owner_id BIGINT REFERENCES users (id) ON DELETE CASCADE;
+UPDATE projects SET owner_id = 1 WHERE id <> 1;
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_name_key;
+ALTER TABLE projects ADD CONSTRAINT projects_owner_name_key UNIQUE (owner_id, name);
+
+ALTER TABLE tags ADD COLUMN owner_id BIGINT NOT NULL DEFAULT 1 REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE tags ALTER COLUMN owner_id DROP DEFAULT;
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+ALTER TABLE tags ADD CONSTRAINT tags_owner_name_key UNIQUE (owner_id, name);
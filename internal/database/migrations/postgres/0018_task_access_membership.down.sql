@@ -0,0 +1,5 @@
+DROP VIEW IF EXISTS task_access;
+CREATE VIEW task_access (task_id, user_id) AS
+SELECT id, owner_id FROM todolist
+UNION
+SELECT todolist.id, project_access.user_id FROM todolist JOIN project_access ON project_access.project_id = todolist.project_id;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS todolist (
+    id INTEGER PRIMARY KEY,
+    date VARCHAR(8),
+    title TEXT,
+    description TEXT,
+    status TEXT
+);
+
+CREATE INDEX IF NOT EXISTS indexdate ON todolist (date);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS todolist_fts_update;
+DROP TRIGGER IF EXISTS todolist_fts_delete;
+DROP TRIGGER IF EXISTS todolist_fts_insert;
+DROP TABLE IF EXISTS todolist_fts;
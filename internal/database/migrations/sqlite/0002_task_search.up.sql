@@ -0,0 +1,22 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS todolist_fts USING fts5(
+    title,
+    description,
+    content = 'todolist',
+    content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO todolist_fts (todolist_fts) VALUES ('rebuild');
+
+CREATE TRIGGER IF NOT EXISTS todolist_fts_insert AFTER INSERT ON todolist BEGIN
+    INSERT INTO todolist_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todolist_fts_delete AFTER DELETE ON todolist BEGIN
+    INSERT INTO todolist_fts (todolist_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todolist_fts_update AFTER UPDATE OF title, description ON todolist BEGIN
+    INSERT INTO todolist_fts (todolist_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+    INSERT INTO todolist_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
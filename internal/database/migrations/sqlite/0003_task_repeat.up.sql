@@ -0,0 +1 @@
+ALTER TABLE todolist ADD COLUMN repeat TEXT;
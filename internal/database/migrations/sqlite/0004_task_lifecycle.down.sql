@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS todolist_status_idx;
+ALTER TABLE todolist DROP COLUMN completed_at;
+ALTER TABLE todolist DROP COLUMN updated_at;
+ALTER TABLE todolist DROP COLUMN created_at;
@@ -0,0 +1,16 @@
+ALTER TABLE todolist ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE todolist ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE todolist ADD COLUMN completed_at TIMESTAMP;
+
+UPDATE todolist SET status = CASE
+    WHEN status IN ('done', 'Выполнено') THEN 'done'
+    WHEN status IN ('in_progress', 'blocked', 'cancelled') THEN status
+    ELSE 'todo'
+END;
+
+UPDATE todolist SET
+    created_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP,
+    completed_at = CASE WHEN status = 'done' THEN CURRENT_TIMESTAMP END;
+
+CREATE INDEX IF NOT EXISTS todolist_status_idx ON todolist (status);
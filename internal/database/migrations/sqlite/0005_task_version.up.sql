@@ -0,0 +1 @@
+ALTER TABLE todolist ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
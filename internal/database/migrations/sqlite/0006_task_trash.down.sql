@@ -0,0 +1,3 @@
+DELETE FROM todolist WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS todolist_deleted_at_idx;
+ALTER TABLE todolist DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS task_events_no_delete;
+DROP TRIGGER IF EXISTS task_events_no_update;
+DROP TABLE IF EXISTS task_events;
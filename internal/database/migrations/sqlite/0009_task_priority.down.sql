@@ -0,0 +1,7 @@
+ALTER TABLE task_revisions DROP COLUMN due_time;
+ALTER TABLE task_revisions DROP COLUMN priority;
+
+DROP INDEX IF EXISTS todolist_priority_idx;
+
+ALTER TABLE todolist DROP COLUMN due_time;
+ALTER TABLE todolist DROP COLUMN priority;
@@ -0,0 +1,7 @@
+ALTER TABLE todolist ADD COLUMN priority INTEGER NOT NULL DEFAULT 4 CHECK (priority BETWEEN 1 AND 4);
+ALTER TABLE todolist ADD COLUMN due_time VARCHAR(5) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS todolist_priority_idx ON todolist (priority, date, due_time);
+
+ALTER TABLE task_revisions ADD COLUMN priority INTEGER NOT NULL DEFAULT 4;
+ALTER TABLE task_revisions ADD COLUMN due_time VARCHAR(5) NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS todolist_project_idx;
+
+ALTER TABLE todolist DROP COLUMN position;
+ALTER TABLE todolist DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS projects;
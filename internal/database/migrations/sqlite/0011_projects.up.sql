@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Входящие: проект по умолчанию, в который попадают задачи без проекта
+INSERT INTO projects (id, name, created_at, updated_at) VALUES (1, 'Inbox', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- SQLite не позволяет добавить столбец со ссылкой REFERENCES и непустым значением по умолчанию,
+-- поэтому существование проекта проверяет приложение
+ALTER TABLE todolist ADD COLUMN project_id INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE todolist ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+UPDATE todolist SET position = id;
+
+CREATE INDEX IF NOT EXISTS todolist_project_idx ON todolist (project_id, position);
@@ -0,0 +1,10 @@
+-- Порядок задач проекта задается строковым рангом: задачи упорядочены по sort_rank побайтово,
+-- и для переноса задачи между двумя другими достаточно изменить ранг одной задачи.
+-- Ранг не может оканчиваться на 0, иначе между ним и соседним рангом может не найтись места.
+ALTER TABLE todolist ADD COLUMN sort_rank TEXT NOT NULL DEFAULT '';
+UPDATE todolist SET sort_rank = printf('%09d', position) || 'i';
+
+DROP INDEX IF EXISTS todolist_project_idx;
+ALTER TABLE todolist DROP COLUMN position;
+
+CREATE INDEX IF NOT EXISTS todolist_project_idx ON todolist (project_id, sort_rank);
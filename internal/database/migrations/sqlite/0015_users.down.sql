@@ -0,0 +1,46 @@
+-- Одноименные теги разных пользователей объединяются, а одноименные проекты получают в имени свой ID
+CREATE TABLE tags_shared (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+INSERT INTO tags_shared (id, name, created_at) SELECT MIN(id), name, MIN(created_at) FROM tags GROUP BY name;
+
+CREATE TABLE task_tags_shared (
+    task_id INTEGER NOT NULL REFERENCES todolist (id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags_shared (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+INSERT OR IGNORE INTO task_tags_shared (task_id, tag_id)
+SELECT task_tags.task_id, tags_shared.id FROM task_tags
+JOIN tags ON tags.id = task_tags.tag_id
+JOIN tags_shared ON tags_shared.name = tags.name;
+
+DROP TABLE task_tags;
+DROP TABLE tags;
+ALTER TABLE tags_shared RENAME TO tags;
+ALTER TABLE task_tags_shared RENAME TO task_tags;
+CREATE INDEX IF NOT EXISTS task_tags_tag_idx ON task_tags (tag_id);
+
+CREATE TABLE projects_shared (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+INSERT INTO projects_shared (id, name, description, archived_at, created_at, updated_at)
+SELECT id, CASE WHEN owner_id IS NULL OR owner_id = 1 THEN name ELSE name || ' #' || id END,
+    description, archived_at, created_at, updated_at
+FROM projects;
+DROP TABLE projects;
+ALTER TABLE projects_shared RENAME TO projects;
+
+DROP INDEX IF EXISTS task_events_owner_idx;
+ALTER TABLE task_events DROP COLUMN owner_id;
+
+DROP INDEX IF EXISTS todolist_owner_idx;
+ALTER TABLE todolist DROP COLUMN owner_id;
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    login TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Пользователь по умолчанию: ему принадлежат задачи, созданные до появления пользователей,
+-- от его имени работают запросы без входа и после входа по паролю TODO_PASSWORD.
+-- Пароля у него нет, поэтому войти под ним по логину нельзя.
+INSERT INTO users (id, login, created_at, updated_at) VALUES (1, 'default', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- SQLite не позволяет добавить столбец со ссылкой REFERENCES и непустым значением по умолчанию,
+-- поэтому существование владельца проверяет приложение
+ALTER TABLE todolist ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS todolist_owner_idx ON todolist (owner_id, deleted_at);
+
+-- Журнал хранит владельца задачи, чтобы история окончательно удаленных задач оставалась видна только ему
+ALTER TABLE task_events ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS task_events_owner_idx ON task_events (owner_id, id);
+
+-- Имена проектов и тегов уникальны в пределах владельца. Ограничение UNIQUE в SQLite нельзя снять,
+-- поэтому таблицы пересоздаются. Входящие общие для всех пользователей, у них нет владельца.
+CREATE TABLE projects_owned (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (owner_id, name)
+);
+INSERT INTO projects_owned (id, owner_id, name, description, archived_at, created_at, updated_at)
+SELECT id, CASE WHEN id = 1 THEN NULL ELSE 1 END, name, description, archived_at, created_at, updated_at FROM projects;
+DROP TABLE projects;
+ALTER TABLE projects_owned RENAME TO projects;
+
+-- task_tags ссылается на tags, поэтому пересоздается вместе с ней: удаление tags удалило бы связи каскадно
+CREATE TABLE tags_owned (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (owner_id, name)
+);
+INSERT INTO tags_owned (id, owner_id, name, created_at) SELECT id, 1, name, created_at FROM tags;
+
+CREATE TABLE task_tags_owned (
+    task_id INTEGER NOT NULL REFERENCES todolist (id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags_owned (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+INSERT INTO task_tags_owned (task_id, tag_id) SELECT task_id, tag_id FROM task_tags;
+
+DROP TABLE task_tags;
+DROP TABLE tags;
+ALTER TABLE tags_owned RENAME TO tags;
+ALTER TABLE task_tags_owned RENAME TO task_tags;
+CREATE INDEX IF NOT EXISTS task_tags_tag_idx ON task_tags (tag_id);
@@ -0,0 +1,4 @@
+DROP VIEW IF EXISTS task_access;
+DROP VIEW IF EXISTS project_access;
+DROP TABLE IF EXISTS project_invitations;
+DROP TABLE IF EXISTS project_members;
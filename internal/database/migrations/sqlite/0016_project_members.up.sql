@@ -0,0 +1,36 @@
+-- Участники проекта и их роли: viewer читает задачи проекта, editor еще и изменяет их,
+-- admin еще и управляет проектом и его участниками. Владелец проекта в таблицу не попадает.
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS project_members_user_idx ON project_members (user_id);
+
+-- Приглашения в проект ждут, пока приглашенный пользователь их не примет или не отклонит
+CREATE TABLE IF NOT EXISTS project_invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'admin')),
+    invited_by INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS project_invitations_user_idx ON project_invitations (user_id);
+
+-- project_access - роли пользователей в проектах, включая владельцев
+CREATE VIEW IF NOT EXISTS project_access (project_id, user_id, role) AS
+SELECT id, owner_id, 'owner' FROM projects WHERE owner_id IS NOT NULL
+UNION ALL
+SELECT project_id, user_id, role FROM project_members;
+
+-- task_access - задачи, доступные пользователям: собственные и задачи проектов, в которых у них есть роль
+CREATE VIEW IF NOT EXISTS task_access (task_id, user_id) AS
+SELECT id, owner_id FROM todolist
+UNION
+SELECT todolist.id, project_access.user_id FROM todolist JOIN project_access ON project_access.project_id = todolist.project_id;
@@ -0,0 +1,16 @@
+-- Ключи API пользователей для скриптов и интеграций. Ключ показывается один раз при создании,
+-- в таблице хранится только его хеш SHA-256 и начало для отображения в списке ключей.
+-- scopes - разрешения ключа через пробел, например 'tasks:read tasks:write'.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys (user_id);
@@ -0,0 +1,8 @@
+-- task_access - задачи, доступные пользователям: собственные задачи во Входящих и задачи проектов,
+-- в которых у них есть роль. Исключенный из проекта участник теряет доступ и к задачам, которые создал в нем.
+DROP VIEW IF EXISTS task_access;
+CREATE VIEW task_access (task_id, user_id) AS
+SELECT todolist.id, todolist.owner_id FROM todolist
+JOIN projects ON projects.id = todolist.project_id AND projects.owner_id IS NULL
+UNION
+SELECT todolist.id, project_access.user_id FROM todolist JOIN project_access ON project_access.project_id = todolist.project_id;
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// uniqueViolationCode код ошибки PostgreSQL unique_violation: нарушено ограничение UNIQUE или PRIMARY KEY
+const uniqueViolationCode = "23505"
+
+// postgresSearchSource ищет по столбцу search, который поддерживается триггером.
+// Конфигурация russian приводит слова к основе, поэтому падеж и число не важны.
+const postgresSearchSource = `(SELECT todolist.*,
+    ts_rank(todolist.search, query)::float8 AS rank,
+    ts_headline('russian', COALESCE(todolist.title, ''), query,
+        'StartSel=` + markOpen + `, StopSel=` + markClose + `, HighlightAll=true') AS title_hl,
+    ts_headline('russian', COALESCE(todolist.description, ''), query,
+        'StartSel=` + markOpen + `, StopSel=` + markClose + `, MaxWords=20, MinWords=5') AS description_hl
+FROM todolist, to_tsquery('russian', ?) AS query
+WHERE todolist.search @@ query) AS todolist`
+
+// postgresDialect реализует dialect для PostgreSQL
+type postgresDialect struct{}
+
+// NewPostgresTaskData создает хранилище задач в PostgreSQL.
+// dsn передается драйверу lib/pq без изменений.
+func NewPostgresTaskData(dsn string) (*TaskData, error) {
+	return newTaskData(postgresDialect{}, dsn)
+}
+
+func (postgresDialect) name() string {
+	return "postgres"
+}
+
+func (postgresDialect) driverName() string {
+	return "postgres"
+}
+
+func (postgresDialect) rebind(query string) string {
+	return sqlx.Rebind(sqlx.DOLLAR, query)
+}
+
+func (postgresDialect) ilike() string {
+	return "ILIKE"
+}
+
+func (postgresDialect) searchSource() string {
+	return postgresSearchSource
+}
+
+// searchExpr строит запрос to_tsquery: слова объединяются через &,
+// слова фразы - оператором следования <->, префикс обозначается :*
+func (postgresDialect) searchExpr(terms []searchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		words := append([]string{}, term.words...)
+		if term.prefix {
+			words[len(words)-1] += ":*"
+		}
+		parts = append(parts, "("+strings.Join(words, " <-> ")+")")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// insert использует RETURNING id, так как lib/pq не поддерживает LastInsertId
+func (d postgresDialect) insert(ctx context.Context, q queryer, query string, args ...any) (int64, error) {
+	var id int64
+	query = strings.TrimSpace(query) + " RETURNING id"
+	err := q.QueryRowContext(ctx, d.rebind(query), args...).Scan(&id)
+	return id, err
+}
+
+func (postgresDialect) uniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
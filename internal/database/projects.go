@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	insertProjectQuery = `
+INSERT INTO projects (name, description, created_at, updated_at, owner_id) VALUES (?, ?, ?, ?, ?)
+`
+	// Изменить можно только проект, в котором у пользователя есть роль: у общих Входящих нет владельца и участников.
+	// Удалить проект может только владелец.
+	updateProjectQuery  = "UPDATE projects SET name = ?, description = ?, updated_at = ? WHERE id = ? AND " + accessibleProject
+	archiveProjectQuery = "UPDATE projects SET archived_at = ?, updated_at = ? WHERE id = ? AND " + accessibleProject
+
+	// accessibleProject отбирает проекты, в которых у пользователя из плейсхолдера есть роль
+	accessibleProject = "id IN (SELECT project_id FROM project_access WHERE user_id = ?)"
+
+	// deleteProjectQuery удаляет проект, только если в нем нет задач, в том числе в корзине
+	deleteProjectQuery = `
+DELETE FROM projects WHERE id = ? AND owner_id = ? AND NOT EXISTS (SELECT 1 FROM todolist WHERE project_id = ?)
+`
+
+	// projectsQuery выбирает проекты, в которых у пользователя есть роль, и общие Входящие
+	// с ролью пользователя и количеством доступных ему задач, не считая задач из корзины.
+	// Оба плейсхолдера - пользователь.
+	projectsQuery = `
+SELECT projects.id, projects.name, projects.description, projects.created_at, projects.updated_at, projects.archived_at,
+    COALESCE(projects.owner_id, 0), COALESCE(project_access.role, ''), COUNT(todolist.id)
+FROM projects
+LEFT JOIN project_access ON project_access.project_id = projects.id AND project_access.user_id = ?
+LEFT JOIN todolist ON todolist.project_id = projects.id AND todolist.deleted_at IS NULL
+    AND (todolist.owner_id = ? OR project_access.role IS NOT NULL)
+WHERE (projects.owner_id IS NULL OR project_access.role IS NOT NULL)
+`
+	projectsGroupBy = " GROUP BY projects.id, project_access.role"
+
+	projectTasksQuery = "SELECT id FROM todolist WHERE project_id = ? AND " + visibleTask + " AND deleted_at IS NULL ORDER BY sort_rank, id"
+)
+
+// ProjectStore описывает хранилище проектов
+type ProjectStore interface {
+	InsertProject(ctx context.Context, project model.Project) (int64, error)
+	GetProject(ctx context.Context, id int64) (model.Project, error)
+	GetProjectByName(ctx context.Context, name string) (model.Project, error)
+	FindProjects(ctx context.Context, archived bool) ([]model.Project, error)
+	UpdateProject(ctx context.Context, project model.Project) (bool, error)
+	ArchiveProject(ctx context.Context, id int64, archived bool) (bool, error)
+	DeleteProject(ctx context.Context, id int64) (bool, error)
+	ReorderTasks(ctx context.Context, projectID int64, taskIDs []int64) (bool, error)
+	MoveTask(ctx context.Context, id, targetID int64, after bool) (bool, error)
+}
+
+// InsertProject создает проект и возвращает его ID
+func (data *TaskData) InsertProject(ctx context.Context, project model.Project) (int64, error) {
+	now := time.Now().UTC()
+	return data.dialect.insert(ctx, data.db, insertProjectQuery, project.Name, project.Description, now, now, OwnerFrom(ctx))
+}
+
+// GetProject возвращает проект по ID, в том числе архивный
+func (data *TaskData) GetProject(ctx context.Context, id int64) (model.Project, error) {
+	owner := OwnerFrom(ctx)
+	query := projectsQuery + "AND projects.id = ?" + projectsGroupBy
+	return scanProject(data.db.QueryRowContext(ctx, data.q(query), owner, owner, id))
+}
+
+// GetProjectByName возвращает собственный проект пользователя или Входящие по имени
+func (data *TaskData) GetProjectByName(ctx context.Context, name string) (model.Project, error) {
+	owner := OwnerFrom(ctx)
+	query := projectsQuery + "AND (projects.owner_id IS NULL OR project_access.role = ?) AND projects.name = ?" + projectsGroupBy
+	return scanProject(data.db.QueryRowContext(ctx, data.q(query), owner, owner, model.RoleOwner, name))
+}
+
+// FindProjects возвращает действующие или, если archived, архивные проекты в порядке создания
+func (data *TaskData) FindProjects(ctx context.Context, archived bool) ([]model.Project, error) {
+	query := projectsQuery + "AND projects.archived_at IS NULL"
+	if archived {
+		query = projectsQuery + "AND projects.archived_at IS NOT NULL"
+	}
+	owner := OwnerFrom(ctx)
+	rows, err := data.db.QueryContext(ctx, data.q(query+projectsGroupBy+" ORDER BY projects.id"), owner, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []model.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// UpdateProject изменяет имя и описание проекта. false означает, что проекта нет.
+func (data *TaskData) UpdateProject(ctx context.Context, project model.Project) (bool, error) {
+	res, err := data.db.ExecContext(ctx, data.q(updateProjectQuery), project.Name, project.Description, time.Now().UTC(), project.Id, OwnerFrom(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// ArchiveProject отправляет проект в архив или, если archived false, возвращает из архива.
+// false означает, что проекта нет.
+func (data *TaskData) ArchiveProject(ctx context.Context, id int64, archived bool) (bool, error) {
+	now := time.Now().UTC()
+	archivedAt := sql.NullTime{Time: now, Valid: archived}
+	res, err := data.db.ExecContext(ctx, data.q(archiveProjectQuery), archivedAt, now, id, OwnerFrom(ctx))
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// DeleteProject удаляет собственный проект пользователя без задач.
+// false означает, что проекта нет или в нем есть задачи.
+func (data *TaskData) DeleteProject(ctx context.Context, id int64) (bool, error) {
+	res, err := data.db.ExecContext(ctx, data.q(deleteProjectQuery), id, OwnerFrom(ctx), id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// ReorderTasks упорядочивает задачи проекта: сначала задачи taskIDs в указанном порядке,
+// затем остальные задачи проекта в прежнем порядке. Задачи из корзины не участвуют.
+// false означает, что какая-то из задач taskIDs не принадлежит проекту.
+func (data *TaskData) ReorderTasks(ctx context.Context, projectID int64, taskIDs []int64) (bool, error) {
+	reordered := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, data.q(projectTasksQuery), projectID, OwnerFrom(ctx))
+		if err != nil {
+			return err
+		}
+		var current []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			current = append(current, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		listed := make(map[int64]bool, len(taskIDs))
+		for _, id := range taskIDs {
+			listed[id] = true
+		}
+		order := append([]int64{}, taskIDs...)
+		for _, id := range current {
+			if listed[id] {
+				delete(listed, id)
+				continue
+			}
+			order = append(order, id)
+		}
+		if len(listed) > 0 {
+			return nil
+		}
+
+		if err := data.setRanks(ctx, tx, order); err != nil {
+			return err
+		}
+		reordered = true
+		return nil
+	})
+	return reordered && err == nil, err
+}
+
+// scanProject считывает проект из строки результата projectsQuery
+func scanProject(row scanner) (model.Project, error) {
+	var (
+		project                          model.Project
+		createdAt, updatedAt, archivedAt sql.NullTime
+	)
+	err := row.Scan(&project.Id, &project.Name, &project.Description, &createdAt, &updatedAt, &archivedAt,
+		&project.OwnerId, &project.Role, &project.TaskCount)
+	project.CreatedAt, project.UpdatedAt, project.ArchivedAt = timePtr(createdAt), timePtr(updatedAt), timePtr(archivedAt)
+	return project, err
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// DefaultSort поле сортировки списка задач по умолчанию
+const DefaultSort = "date"
+
+// sortKey описывает выражение сортировки и способ получить его значение из задачи
+type sortKey struct {
+	expr  string
+	value func(task model.Task) any
+}
+
+// idKey завершает любую сортировку, делая порядок строк однозначным
+var idKey = sortKey{"id", func(task model.Task) any { return task.Id }}
+
+// dateKey и dueTimeKey задают срок выполнения задачи. Задача без времени
+// выполняется до конца дня, поэтому пустое время упорядочено после любого другого.
+var (
+	dateKey    = sortKey{"COALESCE(date, '')", func(task model.Task) any { return task.Date }}
+	dueTimeKey = sortKey{"COALESCE(NULLIF(due_time, ''), '" + endOfDay + "')", func(task model.Task) any {
+		if len(task.Time) == 0 {
+			return endOfDay
+		}
+		return task.Time
+	}}
+)
+
+// endOfDay время срока выполнения задачи без указанного времени
+const endOfDay = "24:00"
+
+// sortFields содержит допустимые поля сортировки списка задач.
+// Имена полей из запроса никогда не попадают в SQL напрямую.
+var sortFields = map[string][]sortKey{
+	"date":   {dateKey},
+	"title":  {{"COALESCE(title, '')", func(task model.Task) any { return task.Title }}},
+	"status": {{"COALESCE(status, '')", func(task model.Task) any { return task.Status }}},
+	"id":     {},
+
+	// position - порядок задач внутри проекта
+	"position": {{"sort_rank", func(task model.Task) any { return task.SortRank }}},
+
+	// due - по сроку выполнения, priority - по приоритету, затем по сроку
+	"due":      {dateKey, dueTimeKey},
+	"priority": {{"priority", func(task model.Task) any { return int64(task.Priority) }}, dateKey, dueTimeKey},
+
+	"relevance": {{"rank", func(task model.Task) any { return task.Rank }}},
+}
+
+// IsSortField проверяет, поддерживается ли сортировка по полю
+func IsSortField(name string) bool {
+	_, ok := sortFields[name]
+	return ok
+}
+
+// cursor содержит значения ключа сортировки последней строки страницы
+type cursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Values []any  `json:"v"`
+}
+
+// encodeCursor кодирует позицию после задачи last в непрозрачную строку
+func encodeCursor(sort string, desc bool, keys []sortKey, last model.Task) string {
+	c := cursor{Sort: sort, Desc: desc, Values: make([]any, len(keys))}
+	for i, key := range keys {
+		c.Values[i] = key.value(last)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор и проверяет, что он выдан для той же сортировки
+func decodeCursor(s string, sort string, desc bool, keys []sortKey) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, taskerror.ErrInvalidCursor
+	}
+	var c cursor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return nil, taskerror.ErrInvalidCursor
+	}
+	if c.Sort != sort || c.Desc != desc || len(c.Values) != len(keys) {
+		return nil, taskerror.ErrInvalidCursor
+	}
+	for i, v := range c.Values {
+		switch v := v.(type) {
+		case string:
+		case json.Number:
+			if c.Values[i], err = v.Int64(); err == nil {
+				continue
+			}
+			if c.Values[i], err = v.Float64(); err != nil {
+				return nil, taskerror.ErrInvalidCursor
+			}
+		default:
+			return nil, taskerror.ErrInvalidCursor
+		}
+	}
+	return c.Values, nil
+}
+
+// taskQuery накапливает условия WHERE и их аргументы
+type taskQuery struct {
+	where []string
+	args  []any
+}
+
+// add добавляет условие, объединяемое с остальными через AND
+func (q *taskQuery) add(cond string, args ...any) {
+	q.where = append(q.where, cond)
+	q.args = append(q.args, args...)
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// keyset строит условие "строка после курсора" для составного ключа сортировки:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func keyset(keys []sortKey, values []any, desc bool) (string, []any) {
+	op := " > ?"
+	if desc {
+		op = " < ?"
+	}
+	var (
+		or   []string
+		args []any
+	)
+	for i := range keys {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, keys[j].expr+" = ?")
+			args = append(args, values[j])
+		}
+		and = append(and, keys[i].expr+op)
+		args = append(args, values[i])
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return "(" + strings.Join(or, " OR ") + ")", args
+}
+
+// listQuery готовый запрос списка задач
+type listQuery struct {
+	// sql текст запроса с плейсхолдерами "?"
+	sql  string
+	args []any
+	// keys ключи сортировки для построения курсора следующей страницы
+	keys []sortKey
+	// scan считывает строку результата
+	scan func(row scanner) (model.Task, error)
+}
+
+// buildListQuery строит запрос списка задач владельца owner по фильтру.
+// Запрашивается на одну строку больше лимита, чтобы определить наличие следующей страницы.
+func buildListQuery(d dialect, filter model.TaskFilter, owner int64) (listQuery, error) {
+	terms := parseSearch(filter.Search)
+
+	sort := filter.Sort
+	fields, ok := sortFields[sort]
+	if !ok || (sort == "relevance" && len(terms) == 0) {
+		return listQuery{}, taskerror.ErrInvalidSort
+	}
+	keys := append(append([]sortKey{}, fields...), idKey)
+
+	// При полнотекстовом поиске источником строк становится подзапрос поиска
+	var q taskQuery
+	source, columns, scan := "todolist", taskColumns, scanTask
+	if len(terms) > 0 {
+		source, columns, scan = d.searchSource(), taskColumns+", "+searchColumns, scanSearchTask
+		q.args = append(q.args, d.searchExpr(terms))
+	}
+
+	q.add(visibleTask, owner)
+	if filter.Trash {
+		q.add("deleted_at IS NOT NULL")
+	} else {
+		q.add("deleted_at IS NULL")
+	}
+	if filter.ProjectId > 0 {
+		q.add("project_id = ?", filter.ProjectId)
+	} else if !filter.Trash {
+		// Задачи архивных проектов видны только в списке своего проекта
+		q.add("project_id NOT IN (SELECT id FROM projects WHERE archived_at IS NOT NULL)")
+	}
+	if len(filter.Statuses) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.Statuses)), ", ")
+		args := make([]any, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			args[i] = status
+		}
+		q.add("status IN ("+placeholders+")", args...)
+	}
+	if filter.Overdue {
+		// Срок прошел, если прошла дата или сегодня уже наступило указанное время
+		today, now := filter.Now.Format(settings.DateFormat), filter.Now.Format(settings.TimeFormat)
+		q.add("status NOT IN (?, ?) AND (date < ? OR (date = ? AND due_time <> '' AND due_time < ?))",
+			model.StatusDone, model.StatusCancelled, today, today, now)
+	}
+	if filter.Actionable {
+		q.add("COALESCE(status, '') NOT IN (?, ?, ?) AND NOT "+blockedTasksCond,
+			model.StatusDone, model.StatusCancelled, model.StatusBlocked, model.StatusDone, model.StatusCancelled)
+	}
+	if len(filter.Tags) > 0 {
+		cond, args := tagFilter(filter.Tags, filter.AllTags)
+		q.add(cond, args...)
+	}
+	if len(filter.DateFrom) > 0 {
+		q.add("date >= ?", filter.DateFrom)
+	}
+	if len(filter.DateTo) > 0 {
+		q.add("date <= ?", filter.DateTo)
+	}
+	if len(filter.Title) > 0 {
+		q.add("title "+d.ilike()+" ? ESCAPE '\\'", "%"+escapeLike(filter.Title)+"%")
+	}
+	if len(filter.Description) > 0 {
+		q.add("description "+d.ilike()+" ? ESCAPE '\\'", "%"+escapeLike(filter.Description)+"%")
+	}
+	if len(filter.Cursor) > 0 {
+		values, err := decodeCursor(filter.Cursor, sort, filter.Desc, keys)
+		if err != nil {
+			return listQuery{}, err
+		}
+		cond, args := keyset(keys, values, filter.Desc)
+		q.add(cond, args...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + columns + " FROM " + source)
+	if len(q.where) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(q.where, " AND "))
+	}
+	direction := " ASC"
+	if filter.Desc {
+		direction = " DESC"
+	}
+	order := make([]string, len(keys))
+	for i, key := range keys {
+		order[i] = key.expr + direction
+	}
+	sb.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	sb.WriteString(" LIMIT ?")
+
+	return listQuery{sql: sb.String(), args: append(q.args, filter.Limit+1), keys: keys, scan: scan}, nil
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// rankDigits цифры рангов задач в порядке возрастания. Ранг - дробная часть числа в системе счисления
+// по основанию len(rankDigits): между любыми двумя рангами можно вставить третий, поэтому перенос задачи
+// изменяет ранг только у нее. Ранг не оканчивается на младшую цифру, иначе за ним могло бы не найтись места.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const (
+	// lastRankQuery выбирает наибольший ранг задач проекта, в том числе задач из корзины
+	lastRankQuery = "SELECT COALESCE(MAX(sort_rank), '') FROM todolist WHERE project_id = ?"
+	taskRankQuery = "SELECT project_id, sort_rank FROM todolist WHERE id = ? AND " + visibleTask + " AND deleted_at IS NULL"
+
+	// prevRankQuery и nextRankQuery выбирают ранг соседней доступной задачи в проекте до и после задачи
+	// с рангом и ID из плейсхолдеров, пропуская переносимую задачу
+	prevRankQuery = `
+SELECT sort_rank FROM todolist
+WHERE project_id = ? AND ` + visibleTask + ` AND deleted_at IS NULL AND (sort_rank < ? OR (sort_rank = ? AND id < ?)) AND id <> ?
+ORDER BY sort_rank DESC, id DESC LIMIT 1
+`
+	nextRankQuery = `
+SELECT sort_rank FROM todolist
+WHERE project_id = ? AND ` + visibleTask + ` AND deleted_at IS NULL AND (sort_rank > ? OR (sort_rank = ? AND id > ?)) AND id <> ?
+ORDER BY sort_rank, id LIMIT 1
+`
+	rankedTasksQuery = "SELECT id FROM todolist WHERE project_id = ? AND " + visibleTask + " ORDER BY sort_rank, id"
+	setRankQuery     = "UPDATE todolist SET sort_rank = ? WHERE id = ? AND " + visibleTask
+)
+
+// rankBetween возвращает ранг между рангами prev и next; пустой prev означает начало списка, пустой next - конец.
+// prev должен быть меньше next.
+func rankBetween(prev, next string) string {
+	if len(next) > 0 {
+		// Общее начало рангов переходит в результат без изменений
+		n := 0
+		for n < len(next) && rankDigit(prev, n) == strings.IndexByte(rankDigits, next[n]) {
+			n++
+		}
+		if n > 0 {
+			return next[:n] + rankBetween(rankTail(prev, n), next[n:])
+		}
+	}
+	low, high := rankDigit(prev, 0), len(rankDigits)
+	if len(next) > 0 {
+		high = strings.IndexByte(rankDigits, next[0])
+	}
+	if high-low > 1 {
+		return string(rankDigits[(low+high)/2])
+	}
+	// Первые цифры соседние: подходит первая цифра next, если за ней что-то есть, иначе ранг продолжает prev
+	if len(next) > 1 {
+		return next[:1]
+	}
+	return string(rankDigits[low]) + rankBetween(rankTail(prev, 1), "")
+}
+
+// rankDigit возвращает значение цифры ранга в позиции i; за концом ранга идут нули
+func rankDigit(rank string, i int) int {
+	if i >= len(rank) {
+		return 0
+	}
+	return strings.IndexByte(rankDigits, rank[i])
+}
+
+// rankTail возвращает ранг без первых n цифр
+func rankTail(rank string, n int) string {
+	if n >= len(rank) {
+		return ""
+	}
+	return rank[n:]
+}
+
+// spreadRanks возвращает n возрастающих рангов одинаковой длины, равномерно распределенных между началом
+// и концом списка, так что между соседними рангами остается место для новых задач
+func spreadRanks(n int) []string {
+	base := int64(len(rankDigits))
+	width, capacity := 1, base
+	for capacity < int64(n+1)*base {
+		width++
+		capacity *= base
+	}
+	step := capacity / int64(n+1)
+	ranks := make([]string, n)
+	digits := make([]byte, width)
+	for i := range ranks {
+		value := step * int64(i+1)
+		for j := width - 1; j >= 0; j-- {
+			digits[j] = rankDigits[value%base]
+			value /= base
+		}
+		ranks[i] = strings.TrimRight(string(digits), rankDigits[:1])
+	}
+	return ranks
+}
+
+// endRank возвращает ранг для задачи, которая становится последней в проекте projectID
+func (data *TaskData) endRank(ctx context.Context, q queryer, projectID int64) (string, error) {
+	var last string
+	if err := q.QueryRowContext(ctx, data.q(lastRankQuery), projectID).Scan(&last); err != nil {
+		return "", err
+	}
+	return rankBetween(last, ""), nil
+}
+
+// MoveTask переносит задачу id вплотную перед задачей targetID или, если after, после нее, изменяя ранг
+// только переносимой задачи. Когда ранг получается длиннее settings.TaskRankMaxLength или между соседями
+// не остается места, ранги задач проекта распределяются заново.
+// false означает, что задачи или задачи targetID нет, они в разных проектах или это одна задача.
+func (data *TaskData) MoveTask(ctx context.Context, id, targetID int64, after bool) (bool, error) {
+	moved := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		projectID, _, err := data.taskRank(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		targetProjectID, _, err := data.taskRank(ctx, tx, targetID)
+		if err != nil {
+			return err
+		}
+		if id == targetID || projectID != targetProjectID {
+			return nil
+		}
+
+		rank, err := data.rankNear(ctx, tx, id, targetID, after)
+		if err != nil {
+			return err
+		}
+		if len(rank) == 0 || len(rank) > settings.TaskRankMaxLength {
+			if err := data.rebalanceRanks(ctx, tx, projectID); err != nil {
+				return err
+			}
+			if rank, err = data.rankNear(ctx, tx, id, targetID, after); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, data.q(setRankQuery), rank, id, OwnerFrom(ctx)); err != nil {
+			return err
+		}
+		moved = true
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return moved && err == nil, err
+}
+
+// taskRank возвращает проект и ранг задачи не из корзины
+func (data *TaskData) taskRank(ctx context.Context, q queryer, id int64) (projectID int64, rank string, err error) {
+	err = q.QueryRowContext(ctx, data.q(taskRankQuery), id, OwnerFrom(ctx)).Scan(&projectID, &rank)
+	return projectID, rank, err
+}
+
+// rankNear возвращает ранг между задачей targetID и ее соседом перед ней или, если after, после нее,
+// не считая задачи id. Пустой ранг означает, что между ними нет места: у соседей одинаковые ранги.
+func (data *TaskData) rankNear(ctx context.Context, tx *sql.Tx, id, targetID int64, after bool) (string, error) {
+	projectID, target, err := data.taskRank(ctx, tx, targetID)
+	if err != nil {
+		return "", err
+	}
+	query := prevRankQuery
+	if after {
+		query = nextRankQuery
+	}
+	var neighbor string
+	err = tx.QueryRowContext(ctx, data.q(query), projectID, OwnerFrom(ctx), target, target, targetID, id).Scan(&neighbor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	prev, next := neighbor, target
+	if after {
+		prev, next = target, neighbor
+	}
+	if len(next) > 0 && prev >= next {
+		return "", nil
+	}
+	return rankBetween(prev, next), nil
+}
+
+// rebalanceRanks заново распределяет ранги всех доступных задач проекта, в том числе задач из корзины,
+// сохраняя их порядок
+func (data *TaskData) rebalanceRanks(ctx context.Context, tx *sql.Tx, projectID int64) error {
+	ids, err := data.rankedTasks(ctx, tx, projectID)
+	if err != nil {
+		return err
+	}
+	return data.setRanks(ctx, tx, ids)
+}
+
+// rankedTasks возвращает ID доступных задач проекта, в том числе задач из корзины, в порядке рангов
+func (data *TaskData) rankedTasks(ctx context.Context, tx *sql.Tx, projectID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, data.q(rankedTasksQuery), projectID, OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// setRanks присваивает задачам ids равномерно распределенные ранги в порядке ids
+func (data *TaskData) setRanks(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	for i, rank := range spreadRanks(len(ids)) {
+		if _, err := tx.ExecContext(ctx, data.q(setRankQuery), rank, ids[i], OwnerFrom(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
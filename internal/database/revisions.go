@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	insertRevisionQuery = `
+INSERT INTO task_revisions (task_id, revision, action, date, title, description, status, repeat, completed_at, deleted_at,
+    priority, due_time, tags, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+	revisionColumns = "task_id, revision, action, date, title, description, status, repeat, completed_at, deleted_at, " +
+		"priority, due_time, tags, created_at"
+	deleteRevisionsQuery = "DELETE FROM task_revisions WHERE task_id = ?"
+
+	// visibleRevisions отбирает снимки задач, доступных пользователю из плейсхолдера
+	visibleRevisions = "task_id IN (SELECT task_id FROM task_access WHERE user_id = ?)"
+	getRevisionQuery = "SELECT " + revisionColumns + " FROM task_revisions WHERE task_id = ? AND revision = ? AND " +
+		visibleRevisions
+	findRevisionsQuery = "SELECT " + revisionColumns + " FROM task_revisions WHERE task_id = ? AND " + visibleRevisions +
+		" ORDER BY revision DESC"
+)
+
+// addRevision сохраняет снимок задачи task с номером, равным ее версии
+func (data *TaskData) addRevision(ctx context.Context, q queryer, action string, task *model.Task) error {
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, data.q(insertRevisionQuery), task.Id, task.Version, action, task.Date, task.Title,
+		task.Description, task.Status, task.Repeat, nullTime(task.CompletedAt), nullTime(task.DeletedAt),
+		task.Priority, task.Time, string(tagsJSON), time.Now().UTC())
+	return err
+}
+
+// deleteRevisions удаляет снимки окончательно удаленной задачи
+func (data *TaskData) deleteRevisions(ctx context.Context, q queryer, id int64) error {
+	_, err := q.ExecContext(ctx, data.q(deleteRevisionsQuery), id)
+	return err
+}
+
+// scanRevision считывает снимок задачи в порядке revisionColumns
+func scanRevision(row scanner) (model.TaskRevision, error) {
+	var (
+		r                      model.TaskRevision
+		status, repeat         sql.NullString
+		completedAt, deletedAt sql.NullTime
+		tags                   string
+	)
+	err := row.Scan(&r.Task.Id, &r.Revision, &r.Action, &r.Task.Date, &r.Task.Title, &r.Task.Description,
+		&status, &repeat, &completedAt, &deletedAt, &r.Task.Priority, &r.Task.Time, &tags, &r.CreatedAt)
+	if err != nil {
+		return r, err
+	}
+	// Снимок всегда содержит список тегов, пустой список означает задачу без тегов
+	if err := json.Unmarshal([]byte(tags), &r.Task.Tags); err != nil {
+		return r, err
+	}
+	r.Task.Version = r.Revision
+	r.Task.Status = status.String
+	r.Task.Repeat = repeat.String
+	r.Task.CompletedAt = timePtr(completedAt)
+	r.Task.DeletedAt = timePtr(deletedAt)
+	r.CreatedAt = r.CreatedAt.UTC()
+	return r, nil
+}
+
+// GetRevision возвращает снимок задачи с номером revision
+func (data *TaskData) GetRevision(ctx context.Context, id int64, revision int64) (model.TaskRevision, error) {
+	return scanRevision(data.db.QueryRowContext(ctx, data.q(getRevisionQuery), id, revision, OwnerFrom(ctx)))
+}
+
+// FindRevisions возвращает снимки задачи от новых к старым
+func (data *TaskData) FindRevisions(ctx context.Context, id int64) ([]model.TaskRevision, error) {
+	rows, err := data.db.QueryContext(ctx, data.q(findRevisionsQuery), id, OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.TaskRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
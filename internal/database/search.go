@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	// searchColumns дополнительные столбцы строки результата полнотекстового поиска
+	searchColumns = "rank, title_hl, description_hl"
+
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// searchTerm одно условие поискового запроса: слово или фраза из нескольких слов.
+// Все условия запроса должны выполняться одновременно.
+type searchTerm struct {
+	words  []string
+	phrase bool
+	prefix bool
+}
+
+// parseSearch разбирает поисковую строку.
+// Поддерживаются фразы в двойных кавычках ("горячая вода") и поиск по префиксу (бассей*).
+// Слова приводятся к нижнему регистру, все символы кроме букв и цифр считаются разделителями.
+func parseSearch(query string) []searchTerm {
+	var terms []searchTerm
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			// Часть внутри кавычек - фраза
+			if words := searchWords(part); len(words) > 0 {
+				terms = append(terms, searchTerm{words: words, phrase: true})
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			prefix := strings.HasSuffix(field, "*")
+			words := searchWords(field)
+			if len(words) == 0 {
+				continue
+			}
+			// Слово, разбитое разделителями (e-mail), ищется как фраза
+			terms = append(terms, searchTerm{words: words, phrase: len(words) > 1, prefix: prefix})
+		}
+	}
+	return terms
+}
+
+// IsSearchQuery проверяет, содержит ли строка хотя бы одно слово для полнотекстового поиска
+func IsSearchQuery(query string) bool {
+	return len(parseSearch(query)) > 0
+}
+
+// searchWords разбивает строку на слова в нижнем регистре
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// russianEndings окончания, отбрасываемые при поиске, от длинных к коротким
+var russianEndings = []string{
+	"иями", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими", "ешь", "ишь",
+	"ая", "яя", "ое", "ее", "ые", "ие", "ий", "ый", "ой", "ей", "ом", "ем", "ам", "ям",
+	"ах", "ях", "ую", "юю", "ов", "ев", "ия", "ья", "ье", "ию", "ью", "ть", "ет", "ит",
+	"ут", "ют", "ат", "ят", "ла", "ло", "ли",
+	"а", "я", "о", "е", "и", "ы", "у", "ю", "ь", "й",
+}
+
+// russianStem отбрасывает типичное окончание русского слова, чтобы поиск
+// не зависел от падежа и числа: "бассейне" и "бассейном" сводятся к "бассейн".
+// Возвращает true, если основу нужно искать как префикс; короткие и
+// нерусские слова возвращаются без изменений и ищутся целиком.
+func russianStem(word string) (string, bool) {
+	if utf8.RuneCountInString(word) < 5 || !unicode.Is(unicode.Cyrillic, []rune(word)[0]) {
+		return word, false
+	}
+	for _, ending := range russianEndings {
+		stem, ok := strings.CutSuffix(word, ending)
+		if ok && utf8.RuneCountInString(stem) >= 4 {
+			return stem, true
+		}
+	}
+	return word, true
+}
+
+// scanSearchTask считывает задачу и результаты поиска в порядке taskColumns, searchColumns
+func scanSearchTask(row scanner) (model.Task, error) {
+	var (
+		r                taskRow
+		rank             float64
+		titleHl, descrHl sql.NullString
+		highlights       model.TaskHighlights
+	)
+	err := row.Scan(append(r.dest(), &rank, &titleHl, &descrHl)...)
+	task := r.result()
+	task.Rank = rank
+	highlights.Title = safeHighlight(titleHl.String)
+	highlights.Description = safeHighlight(descrHl.String)
+	task.Highlights = &highlights
+	return task, err
+}
+
+// safeHighlight экранирует HTML во фрагменте, сохраняя только теги подсветки
+func safeHighlight(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(
+		html.EscapeString(markOpen), markOpen,
+		html.EscapeString(markClose), markClose,
+	).Replace(s)
+}
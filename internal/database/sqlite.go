@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteSearchSource ищет по индексу FTS5. bm25 берется со знаком минус,
+// чтобы большее значение rank означало большую релевантность, как в PostgreSQL.
+// Совпадения в заголовке весят в 10 раз больше совпадений в описании.
+const sqliteSearchSource = `(SELECT todolist.*,
+    -bm25(todolist_fts, 10.0, 1.0) AS rank,
+    highlight(todolist_fts, 0, '` + markOpen + `', '` + markClose + `') AS title_hl,
+    snippet(todolist_fts, 1, '` + markOpen + `', '` + markClose + `', '…', 16) AS description_hl
+FROM todolist_fts JOIN todolist ON todolist.id = todolist_fts.rowid
+WHERE todolist_fts MATCH ?) AS todolist`
+
+// sqliteDialect реализует dialect для SQLite
+type sqliteDialect struct{}
+
+// sqliteOptions параметры подключения драйвера modernc.org/sqlite:
+// ожидание блокировки вместо немедленной ошибки SQLITE_BUSY, проверка внешних ключей
+// и единый текстовый формат времени, пригодный для сравнения строк.
+const sqliteOptions = "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_time_format=sqlite"
+
+// NewSQLiteTaskData создает хранилище задач в файле SQLite.
+// Допускается префикс sqlite:// перед путем к файлу.
+func NewSQLiteTaskData(path string) (*TaskData, error) {
+	path = strings.TrimPrefix(path, "sqlite://")
+	if strings.Contains(path, "?") {
+		path += "&" + sqliteOptions
+	} else {
+		path += "?" + sqliteOptions
+	}
+	return newTaskData(sqliteDialect{}, path)
+}
+
+func (sqliteDialect) name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) driverName() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) rebind(query string) string {
+	return query
+}
+
+// ilike в SQLite без расширения ICU не учитывает регистр только для латиницы
+func (sqliteDialect) ilike() string {
+	return "LIKE"
+}
+
+func (sqliteDialect) searchSource() string {
+	return sqliteSearchSource
+}
+
+// searchExpr строит запрос FTS5. Токенизатор unicode61 не знает русской морфологии,
+// поэтому у длинных русских слов отбрасывается окончание и ищется префикс основы.
+func (sqliteDialect) searchExpr(terms []searchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		prefix := term.prefix
+		words := term.words
+		if !term.phrase && !prefix {
+			words = []string{words[0]}
+			words[0], prefix = russianStem(words[0])
+		}
+		part := `"` + strings.Join(words, " ") + `"`
+		if prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func (sqliteDialect) uniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+func (sqliteDialect) insert(ctx context.Context, q queryer, query string, args ...any) (int64, error) {
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
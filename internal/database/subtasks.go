@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	// subtasksCTE рекурсивно выбирает подзадачи любой вложенности задачи с ID из первого плейсхолдера, доступные
+	// пользователю из второго, вместе с глубиной вложенности, в том числе подзадачи из корзины.
+	// Подзадачи находятся в проекте задачи, поэтому доступны вместе с ней.
+	subtasksCTE = `
+WITH RECURSIVE subtasks (id, depth) AS (
+    SELECT id, 1 FROM todolist WHERE parent_id = ? AND ` + visibleTask + `
+    UNION ALL
+    SELECT todolist.id, subtasks.depth + 1 FROM todolist JOIN subtasks ON todolist.parent_id = subtasks.id
+)
+`
+	// subtaskIDsQuery возвращает подзадачи от самых глубоких к верхним
+	subtaskIDsQuery  = subtasksCTE + "SELECT id FROM subtasks ORDER BY depth DESC, id"
+	findSubtaskQuery = subtasksCTE + "SELECT " + taskColumns + " FROM todolist WHERE id IN (SELECT id FROM subtasks) AND deleted_at IS NULL ORDER BY sort_rank, id"
+
+	// progressQuery считает выполненные и все подзадачи, кроме отмененных и удаленных, для нескольких задач сразу
+	progressQuery = `
+WITH RECURSIVE subtasks (root, id, status) AS (
+    SELECT parent_id, id, status FROM todolist WHERE parent_id IN (%s) AND ` + visibleTask + ` AND deleted_at IS NULL
+    UNION ALL
+    SELECT subtasks.root, todolist.id, todolist.status FROM todolist JOIN subtasks ON todolist.parent_id = subtasks.id
+    WHERE todolist.deleted_at IS NULL
+)
+SELECT root, COUNT(CASE WHEN status = ? THEN 1 END), COUNT(*) FROM subtasks WHERE COALESCE(status, '') <> ? GROUP BY root
+`
+	completeSubtaskQuery = `
+UPDATE todolist SET status = ?, completed_at = ?, updated_at = ?, version = version + 1
+WHERE id = ? AND ` + visibleTask + ` AND deleted_at IS NULL AND COALESCE(status, '') NOT IN (?, ?)
+`
+	deleteSubtaskQuery = `
+UPDATE todolist SET deleted_at = ?, version = version + 1
+WHERE id = ? AND ` + visibleTask + ` AND deleted_at IS NULL
+`
+	restoreSubtaskQuery = `
+UPDATE todolist SET deleted_at = NULL, updated_at = ?, version = version + 1
+WHERE id = ? AND ` + visibleTask + ` AND deleted_at = ?
+`
+	moveSubtaskQuery = `
+UPDATE todolist SET project_id = ?, updated_at = ?, version = version + 1,
+    sort_rank = ?
+WHERE id = ? AND ` + visibleTask + ` AND project_id <> ?
+`
+	taskProjectQuery = "SELECT project_id FROM todolist WHERE id = ? AND " + visibleTask
+
+	// touchAncestorsQuery увеличивает версии задачи из плейсхолдера и всех задач, подзадачей которых она является
+	touchAncestorsQuery = `
+WITH RECURSIVE ancestors (id) AS (
+    SELECT CAST(? AS BIGINT)
+    UNION
+    SELECT todolist.parent_id FROM todolist JOIN ancestors ON todolist.id = ancestors.id WHERE todolist.parent_id IS NOT NULL
+)
+UPDATE todolist SET version = version + 1 WHERE id IN (SELECT id FROM ancestors)
+`
+)
+
+// subtaskIDs возвращает ID всех подзадач задачи id, в том числе из корзины, от самых глубоких к верхним
+func (data *TaskData) subtaskIDs(ctx context.Context, q queryer, id int64) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, data.q(subtaskIDsQuery), id, OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var subtaskID int64
+		if err := rows.Scan(&subtaskID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, subtaskID)
+	}
+	return ids, rows.Err()
+}
+
+// FindSubtasks возвращает подзадачи любой вложенности задачи id, кроме подзадач из корзины,
+// в порядке задач проекта
+func (data *TaskData) FindSubtasks(ctx context.Context, id int64) ([]model.Task, error) {
+	tasks, err := data.queryTasks(ctx, scanTask, findSubtaskQuery, id, OwnerFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if err := data.loadTags(ctx, data.db, tasks); err != nil {
+		return nil, err
+	}
+	if err := data.loadProgress(ctx, data.db, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, data.loadBlockers(ctx, data.db, tasks)
+}
+
+// loadProgress заполняет выполнение подзадач у задач, у которых есть подзадачи
+func (data *TaskData) loadProgress(ctx context.Context, q queryer, tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(tasks))
+	args := make([]any, 0, len(tasks)+3)
+	for i, task := range tasks {
+		index[task.Id] = i
+		args = append(args, task.Id)
+	}
+	args = append(args, OwnerFrom(ctx), model.StatusDone, model.StatusCancelled)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tasks)), ", ")
+	rows, err := q.QueryContext(ctx, data.q(strings.Replace(progressQuery, "%s", placeholders, 1)), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			root     int64
+			progress model.TaskProgress
+		)
+		if err := rows.Scan(&root, &progress.Done, &progress.Total); err != nil {
+			return err
+		}
+		if progress.Total > 0 {
+			progress.Percent = progress.Done * 100 / progress.Total
+			tasks[index[root]].Progress = &progress
+		}
+	}
+	return rows.Err()
+}
+
+// CompleteSubtasks переводит в статус done все невыполненные и неотмененные подзадачи задачи id
+// и возвращает их количество. Каждая подзадача записывается в журнал как выполненная.
+func (data *TaskData) CompleteSubtasks(ctx context.Context, id int64, completedAt time.Time) (int64, error) {
+	var completed int64
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		ids, err := data.subtaskIDs(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, subtaskID := range ids {
+			changed, err := data.changeInTx(ctx, tx, subtaskID, model.ActionDone, data.execOne(ctx, completeSubtaskQuery,
+				model.StatusDone, completedAt.UTC(), now, subtaskID, OwnerFrom(ctx), model.StatusDone, model.StatusCancelled))
+			if err != nil {
+				return err
+			}
+			if changed {
+				completed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return completed, nil
+}
+
+// cascadeSubtasks применяет к каждой подзадаче задачи id изменение update, записывая его в журнал с действием action.
+// Подзадачи обходятся от самых глубоких к верхним; подзадачи, которые update не изменил, пропускаются.
+func (data *TaskData) cascadeSubtasks(ctx context.Context, tx *sql.Tx, id int64, action string, update func(subtaskID int64) func(tx *sql.Tx) (bool, error)) error {
+	ids, err := data.subtaskIDs(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	for _, subtaskID := range ids {
+		if _, err := data.changeInTx(ctx, tx, subtaskID, action, update(subtaskID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveSubtasks переносит подзадачи задачи id в ее проект, если задачу перенесли в другой проект
+func (data *TaskData) moveSubtasks(ctx context.Context, tx *sql.Tx, id int64) error {
+	var projectID int64
+	if err := tx.QueryRowContext(ctx, data.q(taskProjectQuery), id, OwnerFrom(ctx)).Scan(&projectID); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return data.cascadeSubtasks(ctx, tx, id, model.ActionUpdate, func(subtaskID int64) func(tx *sql.Tx) (bool, error) {
+		return func(tx *sql.Tx) (bool, error) {
+			rank, err := data.endRank(ctx, tx, projectID)
+			if err != nil {
+				return false, err
+			}
+			return data.execOne(ctx, moveSubtaskQuery, projectID, now, rank, subtaskID, OwnerFrom(ctx), projectID)(tx)
+		}
+	})
+}
+
+// touchAncestors увеличивает версии родительских задач любой вложенности, если изменение подзадачи
+// от before к after меняет их выполнение подзадач progress: подзадача создана, выполнена, отменена,
+// возвращена в работу, перенесена в корзину или из нее либо перенесена к другой родительской задаче
+func (data *TaskData) touchAncestors(ctx context.Context, tx *sql.Tx, before, after *model.Task) error {
+	beforeParent, beforeState := progressKey(before)
+	afterParent, afterState := progressKey(after)
+	if beforeParent == afterParent && beforeState == afterState {
+		return nil
+	}
+	for _, parent := range []int64{beforeParent, afterParent} {
+		if parent == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, data.q(touchAncestorsQuery), parent); err != nil {
+			return err
+		}
+		if beforeParent == afterParent {
+			break
+		}
+	}
+	return nil
+}
+
+// progressKey возвращает родительскую задачу подзадачи и то, как подзадача учитывается в progress, см. progressQuery.
+// Задача без родителя, удаленная или перенесенная в корзину не учитывается: родитель 0.
+func progressKey(task *model.Task) (int64, string) {
+	if task == nil || task.ParentId == nil || task.DeletedAt != nil {
+		return 0, ""
+	}
+	switch task.Status {
+	case model.StatusDone, model.StatusCancelled:
+		return *task.ParentId, task.Status
+	}
+	return *task.ParentId, ""
+}
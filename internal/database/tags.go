@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	insertTagQuery     = "INSERT INTO tags (name, created_at, owner_id) VALUES (?, ?, ?)"
+	updateTagQuery     = "UPDATE tags SET name = ? WHERE id = ? AND owner_id = ?"
+	deleteTagQuery     = "DELETE FROM tags WHERE id = ? AND owner_id = ?"
+	clearTaskTagsQuery = "DELETE FROM task_tags WHERE task_id = ?"
+	insertTaskTagQuery = "INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?)"
+
+	// touchTaggedQuery увеличивает версии задач, отмеченных тегом владельца из последнего плейсхолдера:
+	// после переименования или удаления тега у них меняется tags
+	touchTaggedQuery = `
+UPDATE todolist SET version = version + 1
+WHERE id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.id = ? AND tags.owner_id = ?)
+`
+
+	// ensureTagQuery и taskTagIDQuery создают и находят тег владельца задачи из последнего плейсхолдера:
+	// участник проекта отмечает чужие задачи тегами их владельца
+	ensureTagQuery = `
+INSERT INTO tags (name, created_at, owner_id) SELECT ?, ?, owner_id FROM todolist WHERE id = ?
+ON CONFLICT (owner_id, name) DO NOTHING
+`
+	taskTagIDQuery = "SELECT id FROM tags WHERE name = ? AND owner_id = (SELECT owner_id FROM todolist WHERE id = ?)"
+
+	// tagsQuery выбирает теги владельца из плейсхолдера с количеством задач, не считая задач из корзины
+	tagsQuery = `
+SELECT tags.id, tags.name, COUNT(todolist.id) FROM tags
+LEFT JOIN task_tags ON task_tags.tag_id = tags.id
+LEFT JOIN todolist ON todolist.id = task_tags.task_id AND todolist.deleted_at IS NULL
+WHERE tags.owner_id = ?
+`
+	tagsGroupBy = " GROUP BY tags.id, tags.name"
+)
+
+// TagStore описывает хранилище тегов задач
+type TagStore interface {
+	InsertTag(ctx context.Context, name string) (int64, error)
+	GetTag(ctx context.Context, id int64) (model.Tag, error)
+	GetTagByName(ctx context.Context, name string) (model.Tag, error)
+	FindTags(ctx context.Context, prefix string, limit int) ([]model.Tag, error)
+	UpdateTag(ctx context.Context, tag model.Tag) (bool, error)
+	DeleteTag(ctx context.Context, id int64) (bool, error)
+}
+
+// InsertTag создает тег и возвращает его ID
+func (data *TaskData) InsertTag(ctx context.Context, name string) (int64, error) {
+	return data.dialect.insert(ctx, data.db, insertTagQuery, name, time.Now().UTC(), OwnerFrom(ctx))
+}
+
+// GetTag возвращает тег по ID
+func (data *TaskData) GetTag(ctx context.Context, id int64) (model.Tag, error) {
+	return scanTag(data.db.QueryRowContext(ctx, data.q(tagsQuery+"AND tags.id = ?"+tagsGroupBy), OwnerFrom(ctx), id))
+}
+
+// GetTagByName возвращает тег по имени
+func (data *TaskData) GetTagByName(ctx context.Context, name string) (model.Tag, error) {
+	return scanTag(data.db.QueryRowContext(ctx, data.q(tagsQuery+"AND tags.name = ?"+tagsGroupBy), OwnerFrom(ctx), name))
+}
+
+// FindTags возвращает до limit тегов, имена которых начинаются с prefix.
+// Чаще используемые теги идут первыми, что удобно для автодополнения.
+func (data *TaskData) FindTags(ctx context.Context, prefix string, limit int) ([]model.Tag, error) {
+	query := tagsQuery
+	args := []any{OwnerFrom(ctx)}
+	if len(prefix) > 0 {
+		query += "AND tags.name LIKE ? ESCAPE '\\'"
+		args = append(args, escapeLike(prefix)+"%")
+	}
+	query += tagsGroupBy + " ORDER BY COUNT(todolist.id) DESC, tags.name LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := data.db.QueryContext(ctx, data.q(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []model.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// UpdateTag переименовывает тег. false означает, что тега нет.
+func (data *TaskData) UpdateTag(ctx context.Context, tag model.Tag) (bool, error) {
+	return data.changeTag(ctx, tag.Id, updateTagQuery, tag.Name, tag.Id, OwnerFrom(ctx))
+}
+
+// DeleteTag удаляет тег и снимает его со всех задач. false означает, что тега нет.
+func (data *TaskData) DeleteTag(ctx context.Context, id int64) (bool, error) {
+	return data.changeTag(ctx, id, deleteTagQuery, id, OwnerFrom(ctx))
+}
+
+// changeTag изменяет или удаляет тег id запросом query и в той же транзакции увеличивает версии задач с этим тегом
+func (data *TaskData) changeTag(ctx context.Context, id int64, query string, args ...any) (bool, error) {
+	changed := false
+	err := data.withTx(ctx, func(tx *sql.Tx) error {
+		// Версии увеличиваются до изменения: удаление тега снимает его с задач
+		if _, err := tx.ExecContext(ctx, data.q(touchTaggedQuery), id, OwnerFrom(ctx)); err != nil {
+			return err
+		}
+		var err error
+		changed, err = data.execOne(ctx, query, args...)(tx)
+		return err
+	})
+	return changed && err == nil, err
+}
+
+// scanTag считывает тег из строки результата tagsQuery
+func scanTag(row scanner) (model.Tag, error) {
+	var tag model.Tag
+	err := row.Scan(&tag.Id, &tag.Name, &tag.Count)
+	return tag, err
+}
+
+// setTaskTags заменяет теги задачи, создавая теги, которых еще нет
+func (data *TaskData) setTaskTags(ctx context.Context, q queryer, taskID int64, names []string) error {
+	if _, err := q.ExecContext(ctx, data.q(clearTaskTagsQuery), taskID); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := q.ExecContext(ctx, data.q(ensureTagQuery), name, time.Now().UTC(), taskID); err != nil {
+			return err
+		}
+		var tagID int64
+		if err := q.QueryRowContext(ctx, data.q(taskTagIDQuery), name, taskID).Scan(&tagID); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, data.q(insertTaskTagQuery), taskID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags заполняет теги задач одним запросом
+func (data *TaskData) loadTags(ctx context.Context, q queryer, tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	index := make(map[int64]int, len(tasks))
+	args := make([]any, len(tasks))
+	for i, task := range tasks {
+		index[task.Id] = i
+		args[i] = task.Id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tasks)), ", ")
+	rows, err := q.QueryContext(ctx, data.q(`
+SELECT task_tags.task_id, tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+WHERE task_tags.task_id IN (`+placeholders+`) ORDER BY tags.name`), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			taskID int64
+			name   string
+		)
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		i := index[taskID]
+		tasks[i].Tags = append(tasks[i].Tags, name)
+	}
+	return rows.Err()
+}
+
+// tagFilter возвращает условие выборки задач с тегами names:
+// с любым из тегов или, если all, со всеми сразу
+func tagFilter(names []string, all bool) (string, []any) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	args := make([]any, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	cond := "id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name IN (" +
+		placeholders + ")"
+	if all {
+		cond += " GROUP BY task_tags.task_id HAVING COUNT(*) = ?"
+		args = append(args, len(names))
+	}
+	return cond + ")", args
+}
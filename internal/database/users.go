@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+const (
+	userColumns         = "id, login, name, password_hash, created_at, updated_at"
+	insertUserQuery     = "INSERT INTO users (login, name, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
+	getUserQuery        = "SELECT " + userColumns + " FROM users WHERE id = ?"
+	getUserByLoginQuery = "SELECT " + userColumns + " FROM users WHERE login = ?"
+	updateUserQuery     = "UPDATE users SET name = ?, password_hash = ?, updated_at = ? WHERE id = ?"
+)
+
+// UserStore описывает хранилище пользователей
+type UserStore interface {
+	InsertUser(ctx context.Context, user model.User) (int64, error)
+	GetUser(ctx context.Context, id int64) (model.User, error)
+	GetUserByLogin(ctx context.Context, login string) (model.User, error)
+	UpdateUser(ctx context.Context, user model.User) (bool, error)
+}
+
+// ownerKey ключ контекста, под которым хранится пользователь, от имени которого выполняются запросы
+type ownerKey struct{}
+
+// allOwners значение ownerKey, снимающее ограничение по владельцу, см. WithAllOwners
+const allOwners int64 = -1
+
+// WithOwner возвращает контекст, запросы в котором видят и изменяют только задачи, проекты, теги и журнал
+// пользователя userID и задачи проектов, в которых он участвует, а новые задачи, проекты и теги принадлежат ему
+func WithOwner(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, ownerKey{}, userID)
+}
+
+// WithAllOwners возвращает контекст для фоновых задач сервера: PurgeTrash в нем очищает корзины
+// всех пользователей. Остальные запросы в таком контексте выполняются от имени пользователя по умолчанию.
+func WithAllOwners(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ownerKey{}, allOwners)
+}
+
+// OwnerFrom возвращает пользователя, от имени которого выполняются запросы в контексте.
+// Без пользователя в контексте это пользователь по умолчанию.
+func OwnerFrom(ctx context.Context) int64 {
+	owner, ok := ctx.Value(ownerKey{}).(int64)
+	if !ok || owner <= 0 {
+		return model.DefaultUserId
+	}
+	return owner
+}
+
+// isAllOwners проверяет, создан ли контекст WithAllOwners
+func isAllOwners(ctx context.Context) bool {
+	owner, _ := ctx.Value(ownerKey{}).(int64)
+	return owner == allOwners
+}
+
+// InsertUser создает пользователя и возвращает его ID. Если логин уже занят, в том числе пользователем,
+// который регистрируется одновременно, возвращается ErrLoginTaken.
+func (data *TaskData) InsertUser(ctx context.Context, user model.User) (int64, error) {
+	now := time.Now().UTC()
+	id, err := data.dialect.insert(ctx, data.db, insertUserQuery, user.Login, user.Name, user.PasswordHash, now, now)
+	if data.dialect.uniqueViolation(err) {
+		return 0, taskerror.ErrLoginTaken
+	}
+	return id, err
+}
+
+// GetUser возвращает пользователя по ID
+func (data *TaskData) GetUser(ctx context.Context, id int64) (model.User, error) {
+	return scanUser(data.db.QueryRowContext(ctx, data.q(getUserQuery), id))
+}
+
+// GetUserByLogin возвращает пользователя по логину
+func (data *TaskData) GetUserByLogin(ctx context.Context, login string) (model.User, error) {
+	return scanUser(data.db.QueryRowContext(ctx, data.q(getUserByLoginQuery), login))
+}
+
+// UpdateUser изменяет имя и хеш пароля пользователя. false означает, что пользователя нет.
+func (data *TaskData) UpdateUser(ctx context.Context, user model.User) (bool, error) {
+	res, err := data.db.ExecContext(ctx, data.q(updateUserQuery), user.Name, user.PasswordHash, time.Now().UTC(), user.Id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// scanUser считывает пользователя в порядке userColumns
+func scanUser(row scanner) (model.User, error) {
+	var (
+		user                 model.User
+		createdAt, updatedAt sql.NullTime
+	)
+	err := row.Scan(&user.Id, &user.Login, &user.Name, &user.PasswordHash, &createdAt, &updatedAt)
+	user.CreatedAt, user.UpdatedAt = timePtr(createdAt), timePtr(updatedAt)
+	return user, err
+}
@@ -3,18 +3,346 @@ package taskerror
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
+// Kind категория ошибки, определяющая код ответа HTTP
+type Kind int
+
+const (
+	KindInternal         Kind = iota // 500 - непредвиденная ошибка сервера
+	KindBadRequest                   // 400 - запрос невозможно разобрать
+	KindValidation                   // 422 - запрос разобран, но данные недопустимы
+	KindNotFound                     // 404 - ресурс не найден
+	KindConflict                     // 409 - операция противоречит состоянию ресурса
+	KindTooLarge                     // 413 - тело запроса превышает допустимый размер
+	KindUnsupportedMedia             // 415 - тип содержимого запроса не поддерживается
+	KindPrecondition                 // 412 - не выполнено условие If-Match или If-None-Match
+	KindUnauthorized                 // 401 - запрос без действующего токена доступа
+	KindForbidden                    // 403 - роль пользователя не разрешает операцию
+	KindTooManyRequests              // 429 - клиент превысил ограничение частоты запросов
+)
+
+// Status возвращает код ответа HTTP для категории ошибки
+func (k Kind) Status() int {
+	switch k {
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindUnsupportedMedia:
+		return http.StatusUnsupportedMediaType
+	case KindPrecondition:
+		return http.StatusPreconditionFailed
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindTooManyRequests:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// Error доменная ошибка со стабильным машинным кодом.
+// Для errors.Is ошибка равна ошибке с тем же кодом, а сводная ошибка валидации -
+// также любой из ошибок своих полей.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Fields ошибки отдельных полей запроса
+	Fields []FieldError
+	// Err исходная ошибка
+	Err error
+}
+
+// FieldError ошибка значения одного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Code == e.Code {
+		return true
+	}
+	for _, field := range e.Fields {
+		if field.Code == t.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap возвращает копию ошибки с исходной ошибкой err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// newError создает ошибку, а для ошибок валидации с указанным полем - и ошибку поля
+func newError(kind Kind, code, message, field string) *Error {
+	e := &Error{Kind: kind, Code: code, Message: message}
+	if len(field) > 0 {
+		e.Fields = []FieldError{{Field: field, Code: code, Message: message}}
+	}
+	return e
+}
+
+// Validation создает ошибку валидации поля field
+func Validation(code, message, field string) *Error {
+	return newError(KindValidation, code, message, field)
+}
+
+// BadRequest создает ошибку неразборчивого запроса
+func BadRequest(code, message string) *Error {
+	return newError(KindBadRequest, code, message, "")
+}
+
+// NotFound создает ошибку отсутствующего ресурса
+func NotFound(code, message string) *Error {
+	return newError(KindNotFound, code, message, "")
+}
+
+// Conflict создает ошибку конфликта с состоянием ресурса
+func Conflict(code, message string) *Error {
+	return newError(KindConflict, code, message, "")
+}
+
+// Internal оборачивает непредвиденную ошибку
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal", Message: "internal server error", Err: err}
+}
+
 var (
-	ErrRequireTitle = errors.New("require task title")
-	ErrNotFoundTask = errors.New("not found task")
+	ErrRequireTitle       = Validation("title_required", "require task title", "title")
+	ErrNotFoundTask       = NotFound("task_not_found", "not found task")
+	ErrNotInTrash         = NotFound("task_not_in_trash", "task is not in trash")
+	ErrNoRevision         = NotFound("revision_not_found", "task revision not found")
+	ErrNotFoundDependency = NotFound("dependency_not_found", "not found dependency")
+	ErrNotFoundTag        = NotFound("tag_not_found", "not found tag")
+	ErrInvalidID          = Validation("invalid_id", "task id must be a positive integer", "id")
+
+	ErrRequireProjectName = Validation("name_required", "require project name", "name")
+	ErrNotFoundProject    = NotFound("project_not_found", "not found project")
+	ErrNotFoundMember     = NotFound("member_not_found", "not found project member")
+	ErrNotFoundInvitation = NotFound("invitation_not_found", "not found invitation")
+	ErrRequireKeyName     = Validation("name_required", "require API key name", "name")
+	ErrNotFoundAPIKey     = NotFound("api_key_not_found", "not found API key")
+
+	ErrInvalidCursor     = Validation("invalid_cursor", "invalid cursor", "cursor")
+	ErrInvalidSort       = Validation("invalid_sort", "invalid sort field", "sort")
+	ErrInvalidOrder      = Validation("invalid_order", "invalid order, expected asc or desc", "order")
+	ErrInvalidLimit      = Validation("invalid_limit", "invalid limit", "limit")
+	ErrInvalidDate       = Validation("invalid_date", "invalid date, expected YYYYMMDD", "date")
+	ErrInvalidRepeat     = Validation("invalid_repeat", "invalid repeat rule", "repeat")
+	ErrInvalidStatus     = Validation("invalid_status", "invalid status, expected todo, in_progress, blocked, done or cancelled", "status")
+	ErrInvalidDueTime    = Validation("invalid_due_time", "invalid due time, expected HH:MM", "time")
+	ErrInvalidPriority   = Validation("invalid_priority", "invalid priority, expected 1 - 4", "priority")
+	ErrInvalidTag        = Validation("invalid_tag", "tag must be a non-empty name without commas", "tags")
+	ErrInvalidTagMatch   = Validation("invalid_tag_match", "invalid tag_match, expected any or all", "tag_match")
+	ErrInvalidTagID      = Validation("invalid_id", "tag id must be a positive integer", "id")
+	ErrInvalidProject    = Validation("invalid_project", "project does not exist", "project_id")
+	ErrInvalidProjectID  = Validation("invalid_id", "project id must be a positive integer", "id")
+	ErrInvalidTaskOrder  = Validation("invalid_task_order", "tasks must be distinct tasks of the project", "tasks")
+	ErrInvalidArchived   = Validation("invalid_archived", "invalid archived, expected true or false", "archived")
+	ErrInvalidParent     = Validation("invalid_parent", "parent task does not exist", "parent_id")
+	ErrParentCycle       = Validation("parent_cycle", "task cannot be a subtask of itself or of its subtasks", "parent_id")
+	ErrSubtaskProject    = Validation("subtask_project", "subtask must be in the project of its parent task", "project_id")
+	ErrInvalidMove       = Validation("invalid_move", "exactly one of before and after is required", "before")
+	ErrInvalidMoveTarget = Validation("invalid_move_target", "target must be another task of the same project", "before")
+	ErrIDMismatch        = Validation("id_mismatch", "task id in body does not match path", "id")
+	ErrInvalidLogin      = Validation("invalid_login", "login must be 3 - 32 latin letters, digits, dots, dashes or underscores", "login")
+	ErrWeakPassword      = Validation("weak_password", "password must be 8 - 72 bytes long", "password")
+	ErrWrongPassword     = Validation("wrong_password", "current password is incorrect", "current_password")
+	ErrInvalidRole       = Validation("invalid_role", "invalid role, expected viewer, editor or admin", "role")
+	ErrUnknownUser       = Validation("unknown_user", "user with this login does not exist", "login")
+	ErrInvalidUserID     = Validation("invalid_id", "user id must be a positive integer", "user_id")
+	ErrInvalidInviteID   = Validation("invalid_id", "invitation id must be a positive integer", "id")
+	ErrInvalidScope      = Validation("invalid_scope", "invalid scopes, expected one or more of tasks:read and tasks:write", "scopes")
+	ErrInvalidExpiry     = Validation("invalid_expiry", "expires_at must be in the future", "expires_at")
+	ErrInvalidKeyID      = Validation("invalid_id", "API key id must be a positive integer", "id")
+
+	ErrValidation         = Validation("validation_failed", "request validation failed", "")
+	ErrTooLong            = Validation("too_long", "value is too long", "")
+	ErrDateOutOfRange     = Validation("date_out_of_range", "date is out of the allowed range", "date")
+	ErrInvalidDateRange   = Validation("invalid_date_range", "date_from is after date_to", "date_from")
+	ErrUnknownField       = Validation("unknown_field", "unknown field", "")
+	ErrInvalidType        = Validation("invalid_type", "value has invalid type", "")
+	ErrMalformedBody      = BadRequest("malformed_body", "request body is not valid JSON")
+	ErrBodyTooLarge       = newError(KindTooLarge, "body_too_large", "request body is too large", "")
+	ErrUnsupportedContent = newError(KindUnsupportedMedia, "unsupported_media_type", "content type must be application/json", "")
+
+	ErrUnsupportedPatch = newError(KindUnsupportedMedia, "unsupported_patch_type",
+		"content type must be application/merge-patch+json or application/json-patch+json", "")
+	ErrInvalidPatch    = BadRequest("invalid_patch", "invalid patch document")
+	ErrPatchPath       = Validation("patch_path_not_found", "patch path does not exist", "")
+	ErrPatchTestFailed = Conflict("patch_test_failed", "patch test operation failed")
+
+	ErrIllegalTransition = Conflict("illegal_transition", "illegal status transition")
+	ErrTagExists         = Conflict("tag_exists", "tag with this name already exists")
+	ErrProjectExists     = Conflict("project_exists", "project with this name already exists")
+	ErrProjectArchived   = Conflict("project_archived", "project is archived")
+	ErrProjectNotEmpty   = Conflict("project_not_empty", "project has tasks, including tasks in the trash")
+	ErrInboxProject      = Conflict("inbox_project", "the inbox project cannot be changed, archived or deleted")
+	ErrLoginTaken        = Conflict("login_taken", "user with this login already exists")
+	ErrAlreadyMember     = Conflict("already_member", "user is already a member of the project")
+	ErrAlreadyInvited    = Conflict("already_invited", "user already has a pending invitation to the project")
+	ErrProjectOwner      = Conflict("project_owner", "the project owner cannot be invited, changed or removed")
+	ErrParentInTrash     = Conflict("parent_in_trash", "parent task is in the trash, restore it first")
+	ErrDependencyCycle   = Conflict("dependency_cycle", "dependency would create a cycle")
+	ErrConcurrentUpdate  = Conflict("concurrent_update", "task was modified by another request")
+
+	ErrInvalidAction    = Validation("invalid_action", "invalid action, expected create, update, done, reopen, delete, restore, purge or revert", "action")
+	ErrInvalidTime      = Validation("invalid_time", "invalid time, expected RFC 3339", "")
+	ErrInvalidTimeRange = Validation("invalid_time_range", "from is not before to", "from")
+	ErrInvalidRevision  = Validation("invalid_revision", "revision must be a positive integer less than the current task version", "revision")
+
+	ErrPreconditionFailed = newError(KindPrecondition, "precondition_failed", "task version does not match precondition", "")
+
+	ErrInvalidPassword    = newError(KindUnauthorized, "invalid_password", "invalid password", "password")
+	ErrUnauthorized       = newError(KindUnauthorized, "unauthorized", "authentication required, sign in first", "")
+	ErrTokenExpired       = newError(KindUnauthorized, "token_expired", "token has expired, sign in again", "")
+	ErrInvalidCredentials = newError(KindUnauthorized, "invalid_credentials", "invalid login or password", "")
+	ErrAPIKeyExpired      = newError(KindUnauthorized, "api_key_expired", "API key has expired", "")
+	ErrForbidden          = newError(KindForbidden, "forbidden", "your role in the project does not allow this action", "")
+	ErrInsufficientScope  = newError(KindForbidden, "insufficient_scope", "API key scopes do not allow this request", "")
+	ErrSessionRequired    = newError(KindForbidden, "session_required", "API keys cannot manage API keys, sign in first", "")
+	ErrRateLimited        = newError(KindTooManyRequests, "rate_limited", "too many requests, retry later", "")
 )
 
-// MarshalError преобразует ошибку в формат JSON
-func MarshalError(err error) []byte {
-	type errJson struct {
-		Error string `json:"error"`
+// FieldErrors накапливает ошибки полей, чтобы вернуть клиенту их полный список
+type FieldErrors []FieldError
+
+// Add добавляет ошибку err для поля field. Код берется из доменной причины err,
+// сообщение - из err целиком, поэтому уточнения вида fmt.Errorf("%w: ...") сохраняются.
+func (f *FieldErrors) Add(field string, err error) {
+	*f = append(*f, FieldError{Field: field, Code: From(err).Code, Message: err.Error()})
+}
+
+// Err возвращает nil, если ошибок нет. Единственная ошибка сохраняет свой код,
+// несколько ошибок объединяются в ErrValidation со списком всех полей.
+func (f FieldErrors) Err() error {
+	switch len(f) {
+	case 0:
+		return nil
+	case 1:
+		return &Error{Kind: KindValidation, Code: f[0].Code, Message: f[0].Message, Fields: f}
+	}
+	e := *ErrValidation
+	e.Fields = f
+	return &e
+}
+
+// JSONError приводит ошибку разбора JSON к доменной ошибке с указанием поля, если оно известно
+func JSONError(err error) error {
+	var (
+		tooLarge  *http.MaxBytesError
+		typeError *json.UnmarshalTypeError
+		errs      FieldErrors
+	)
+	switch {
+	case errors.As(err, &tooLarge):
+		return ErrBodyTooLarge
+	case errors.As(err, &typeError):
+		errs.Add(typeError.Field, fmt.Errorf("%w: expected %s", ErrInvalidType, typeError.Type))
+		return errs.Err()
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json не экспортирует тип этой ошибки, имя поля есть только в тексте
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		errs.Add(field, ErrUnknownField)
+		return errs.Err()
+	}
+	return ErrMalformedBody.Wrap(err)
+}
+
+// TransitionError сообщает о недопустимом переходе статуса задачи.
+// errors.Is(err, ErrIllegalTransition) возвращает true для этой ошибки.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrIllegalTransition.Message, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// From приводит любую ошибку к доменной. Ошибки без доменной причины считаются внутренними.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
 	}
-	res, _ := json.Marshal(errJson{Error: err.Error()})
+	return Internal(err)
+}
+
+// Problem тело ответа с ошибкой в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// ProblemContentType тип содержимого ответа с ошибкой
+const ProblemContentType = "application/problem+json"
+
+// NewProblem описывает ошибку для ответа на запрос к instance.
+// Подробности внутренних ошибок клиенту не раскрываются.
+func NewProblem(err error, instance string) Problem {
+	e := From(err)
+	detail := err.Error()
+	if e.Kind == KindInternal {
+		detail = e.Message
+	}
+	status := e.Kind.Status()
+	return Problem{
+		Type:     "urn:todo-list:problem:" + e.Code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
+
+// MarshalError преобразует ошибку в формат JSON {"error": "..."} устаревших маршрутов
+func MarshalError(err error) []byte {
+	res, _ := json.Marshal(map[string]string{"error": NewProblem(err, "").Detail})
 	return res
 }
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIKeysPath базовый путь ресурса ключей API пользователя в API v1
+const APIKeysPath = UsersPath + "/me/keys"
+
+// GetAPIKeys обрабатывает запрос списка ключей API пользователя
+func GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	keys, err := TaskServiceInstance.ListAPIKeys(r.Context())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// PostAPIKey обрабатывает запрос создания ключа API: {"name": "ci", "scopes": ["tasks:read"]}.
+// Ключ возвращается в ответе один раз, позже его узнать нельзя.
+func PostAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var input model.APIKeyInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	key, err := TaskServiceInstance.CreateAPIKey(r.Context(), input)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", APIKeysPath, key.Id))
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, key)
+}
+
+// GetAPIKey обрабатывает запрос ключа API {keyId} без самого ключа
+func GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	key, err := TaskServiceInstance.GetAPIKey(r.Context(), chi.URLParam(r, "keyId"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, key)
+}
+
+// DeleteAPIKey обрабатывает запрос отзыва ключа API {keyId}
+func DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := TaskServiceInstance.RevokeAPIKey(r.Context(), chi.URLParam(r, "keyId")); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// Actor записывает в контекст запроса инициатора изменений для журнала.
+// Пока пользователи не различаются, инициатором считается адрес клиента.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.WithActor(r.Context(), clientIP(r))))
+	})
+}
+
+// clientIP возвращает адрес клиента без порта
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// GetTaskHistory обрабатывает запрос истории изменений задачи
+func GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	filter, err := eventFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	history, err := TaskServiceInstance.TaskHistory(r.Context(), taskID(r), filter)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// GetAudit обрабатывает запрос журнала изменений всех задач
+func GetAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	filter, err := eventFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	events, err := TaskServiceInstance.ListAudit(r.Context(), filter)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// eventFilterFromQuery разбирает параметры выборки журнала изменений:
+// action (можно повторять или перечислять через запятую), from и to в формате RFC 3339, limit и cursor
+func eventFilterFromQuery(query url.Values) (model.EventFilter, error) {
+	filter := model.EventFilter{Cursor: query.Get("cursor")}
+	for _, value := range query["action"] {
+		for _, action := range strings.Split(value, ",") {
+			if action = strings.TrimSpace(action); len(action) > 0 {
+				filter.Actions = append(filter.Actions, action)
+			}
+		}
+	}
+
+	var errs taskerror.FieldErrors
+	for _, bound := range []struct {
+		field string
+		t     *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(bound.field)
+		if len(value) == 0 {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errs.Add(bound.field, taskerror.ErrInvalidTime)
+			continue
+		}
+		*bound.t = parsed
+	}
+	if limit := query.Get("limit"); len(limit) > 0 {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			errs.Add("limit", taskerror.ErrInvalidLimit)
+		}
+		filter.Limit = n
+	}
+	return filter, errs.Err()
+}
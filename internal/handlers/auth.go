@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/auth"
+	"github.com/ZnNr/todo-list/internal/database"
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// TokenCookie имя cookie с токеном доступа
+const TokenCookie = "token"
+
+// AuthInstance выдает и проверяет токены доступа. Если он не задан, токены не принимаются,
+// и все запросы выполняются от имени пользователя по умолчанию.
+var AuthInstance *auth.Authenticator
+
+// SignIn обрабатывает запрос входа по паролю TODO_PASSWORD: возвращает токен пользователя по умолчанию
+// {"token": "..."} и сохраняет его в cookie token на срок действия токена
+func SignIn(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	token, expires, err := AuthInstance.SignIn(input.Password)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	respondToken(w, token, expires)
+}
+
+// respondToken возвращает токен {"token": "..."} и сохраняет его в cookie token до времени expires
+func respondToken(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     TokenCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// apiKeyKey ключ контекста, под которым хранится ключ API, с которым выполняется запрос
+type apiKeyKey struct{}
+
+// apiKeyFrom возвращает ключ API, с которым выполняется запрос, или nil, если запрос выполняется без ключа
+func apiKeyFrom(ctx context.Context) *model.APIKey {
+	key, _ := ctx.Value(apiKeyKey{}).(*model.APIKey)
+	return key
+}
+
+// clientKey ключ контекста, под которым RequireAuth сохраняет клиента, выполняющего запрос, см. RateLimit
+type clientKey struct{}
+
+// bearerToken возвращает токен из заголовка Authorization: Bearer <токен> или пустую строку
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// RequireAuth проверяет ключ API или токен из заголовка Authorization: Bearer, а без заголовка - токен из cookie token,
+// и выполняет запрос от имени пользователя, которому выдан ключ или токен: ему видны только его задачи,
+// а в журнал изменений записывается его логин. Разрешения ключей относятся только к задачам,
+// поэтому действительный ключ API получает отказ ErrInsufficientScope, см. RequireTaskAuth.
+// Без токена запрос выполняется от имени пользователя по умолчанию, если не задан пароль TODO_PASSWORD.
+func RequireAuth(next http.Handler) http.Handler {
+	return requireAuth(next, false)
+}
+
+// RequireTaskAuth работает как RequireAuth, но принимает и ключи API с разрешениями на задачи.
+// Используется только для маршрутов задач.
+func RequireTaskAuth(next http.Handler) http.Handler {
+	return requireAuth(next, true)
+}
+
+// requireAuth проверяет ключ API или токен, keys разрешает выполнять запрос по ключу API
+func requireAuth(next http.Handler, keys bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if auth.IsAPIKey(token) {
+			authenticateKey(w, r, next, token, keys)
+			return
+		}
+		if cookie, err := r.Cookie(TokenCookie); len(token) == 0 && err == nil {
+			token = cookie.Value
+		}
+		if len(token) == 0 || AuthInstance == nil {
+			if AuthInstance.Enabled() {
+				writeErrorAndRespond(w, r, taskerror.ErrUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var user *model.User
+		userID, err := AuthInstance.Verify(token, func(userID int64) (string, error) {
+			found, err := TaskServiceInstance.GetUser(r.Context(), userID)
+			if err != nil {
+				return "", err
+			}
+			user = found
+			return found.PasswordHash, nil
+		})
+		if err != nil {
+			writeErrorAndRespond(w, r, err)
+			return
+		}
+		ctx := database.WithOwner(r.Context(), userID)
+		if user != nil {
+			ctx = database.WithActor(ctx, user.Login)
+		}
+		ctx = context.WithValue(ctx, clientKey{}, fmt.Sprintf("user:%d", userID))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateKey проверяет ключ API и его разрешения: для запросов чтения GET и HEAD нужно разрешение tasks:read,
+// для остальных - tasks:write, а без tasks, то есть не к задачам, ключ не принимается.
+// Запрос выполняется от имени владельца ключа.
+func authenticateKey(w http.ResponseWriter, r *http.Request, next http.Handler, secret string, tasks bool) {
+	key, user, err := TaskServiceInstance.AuthenticateAPIKey(r.Context(), secret)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	if !tasks {
+		writeErrorAndRespond(w, r, taskerror.ErrInsufficientScope)
+		return
+	}
+	scope := model.ScopeTasksWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = model.ScopeTasksRead
+	}
+	if !key.Allows(scope) {
+		writeErrorAndRespond(w, r, taskerror.ErrInsufficientScope)
+		return
+	}
+
+	ctx := database.WithActor(database.WithOwner(r.Context(), user.Id), user.Login)
+	ctx = context.WithValue(context.WithValue(ctx, apiKeyKey{}, key), clientKey{}, fmt.Sprintf("key:%d", key.Id))
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireSession запрещает запрос с ключом API. Им защищены учетная запись, ключи и управление проектами:
+// ключ не может выпустить ключ с более широкими разрешениями, пережить свой отзыв или сменить пароль.
+// Может стоять как перед RequireAuth, так и после него.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKeyFrom(r.Context()) != nil || auth.IsAPIKey(bearerToken(r)) {
+			writeErrorAndRespond(w, r, taskerror.ErrSessionRequired)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
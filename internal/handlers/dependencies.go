@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetDependencies обрабатывает запрос задач, которые блокируют задачу, и задач, которые она блокирует
+func GetDependencies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	deps, err := TaskServiceInstance.GetDependencies(r.Context(), taskID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
+
+// PutDependency обрабатывает запрос добавления зависимости: задача {blockerId} блокирует задачу {taskId}
+func PutDependency(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.AddDependency(r.Context(), taskID(r), chi.URLParam(r, "blockerId"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteDependency обрабатывает запрос удаления зависимости задачи {taskId} от задачи {blockerId}
+func DeleteDependency(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.RemoveDependency(r.Context(), taskID(r), chi.URLParam(r, "blockerId"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetActionableTasks обрабатывает запрос списка задач, которые можно начать: не выполненных,
+// не отмененных, не в статусе blocked и без невыполненных блокирующих задач.
+// Принимает те же параметры, что и GetALLTasks, по умолчанию задачи упорядочены по приоритету и сроку.
+func GetActionableTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	filter, err := taskFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	tasks, err := TaskServiceInstance.ListActionable(r.Context(), filter)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// GetTaskPlan обрабатывает запрос всех невыполненных задач в порядке выполнения с учетом зависимостей
+func GetTaskPlan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	tasks, err := TaskServiceInstance.PlanTasks(r.Context())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/task"
+)
+
+// etag возвращает сильный ETag версии задачи
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// matchETag проверяет, есть ли в списке ETag заголовка header тег версии version или "*".
+// При слабом сравнении (weak) теги с префиксом W/ сравниваются без него, при сильном - не совпадают.
+func matchETag(header string, version int64, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// precondition строит условие изменения задачи из заголовков If-Match и If-None-Match.
+// Без этих заголовков задача изменяется без условий.
+func precondition(r *http.Request) task.Precondition {
+	ifMatch := strings.Join(r.Header.Values("If-Match"), ",")
+	ifNoneMatch := strings.Join(r.Header.Values("If-None-Match"), ",")
+	if len(ifMatch) == 0 && len(ifNoneMatch) == 0 {
+		return nil
+	}
+	return func(version int64) error {
+		if len(ifMatch) > 0 && !matchETag(ifMatch, version, false) {
+			return taskerror.ErrPreconditionFailed
+		}
+		if len(ifNoneMatch) > 0 && matchETag(ifNoneMatch, version, true) {
+			return taskerror.ErrPreconditionFailed
+		}
+		return nil
+	}
+}
@@ -1,197 +1,461 @@
 package handlers
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	taskerror "github.com/ZnNr/todo-list/internal/error"
 	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/ZnNr/todo-list/internal/settings"
 	"github.com/ZnNr/todo-list/internal/task"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TasksPath базовый путь ресурса задач в API v1, TrashPath - корзины удаленных задач,
+// AuditPath - журнала изменений
+const (
+	TasksPath = "/api/v1/tasks"
+	TrashPath = "/api/v1/trash"
+	AuditPath = "/api/v1/audit"
 )
 
 var TaskServiceInstance task.TaskService
 
 // taskFromRequestBody извлекает задачу из тела запроса
-func taskFromRequestBody(r *http.Request) (model.Task, error) {
+func taskFromRequestBody(w http.ResponseWriter, r *http.Request) (model.Task, error) {
 	var task model.Task
+	err := decodeJSON(w, r, &task)
+	return task, err
+}
 
-	buff := bytes.Buffer{}
+// decodeJSON строго разбирает тело запроса: тип содержимого должен быть application/json,
+// размер тела не больше settings.RequestBodyLimit, неизвестные поля и данные после объекта запрещены
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return taskerror.ErrUnsupportedContent
+	}
 
-	_, err := buff.ReadFrom(r.Body)
-	if err != nil {
-		return model.Task{}, err
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, settings.RequestBodyLimit))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return taskerror.JSONError(err)
 	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return taskerror.ErrMalformedBody.Wrap(errors.New("unexpected data after JSON object"))
+	}
+	return nil
+}
 
-	err = json.Unmarshal(buff.Bytes(), &task)
-	return task, err
+// taskID возвращает идентификатор задачи из параметра пути {taskId},
+// а для устаревших маршрутов - из параметра запроса id
+func taskID(r *http.Request) string {
+	if id := chi.URLParam(r, "taskId"); len(id) > 0 {
+		return id
+	}
+	return r.URL.Query().Get("id")
 }
 
+// DonePostTask обрабатывает запрос на выполнение задачи
 func DonePostTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	id := r.URL.Query().Get("id")
-	err := TaskServiceInstance.DoneTask(id)
+	err := TaskServiceInstance.DoneTask(r.Context(), taskID(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
-	w.Write([]byte("{}"))
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// ReopenTask обрабатывает запрос на возврат выполненной или отмененной задачи в работу
+func ReopenTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.ReopenTask(r.Context(), taskID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteTask обрабатывает запрос на удаление задачи в корзину
 func DeleteTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	id := r.URL.Query().Get("id")
-	err := TaskServiceInstance.DeleteTask(id)
+	err := TaskServiceInstance.DeleteTask(r.Context(), taskID(r), precondition(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
-	w.Write([]byte("{}"))
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// PostTask обрабатывает POST запрос для создания задачи
-func PostTask(w http.ResponseWriter, r *http.Request) {
+// RestoreTask обрабатывает запрос на возврат задачи из корзины
+func RestoreTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	task, err := taskFromRequestBody(r)
+	err := TaskServiceInstance.RestoreTask(r.Context(), taskID(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTrash обрабатывает запрос на получение списка задач из корзины.
+// Параметры запроса те же, что у GetALLTasks.
+func GetTrash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	id, err := TaskServiceInstance.CreateTask(task)
+	filter, err := taskFilterFromQuery(r.URL.Query())
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
 
-	responseBody, err := json.Marshal(struct {
-		Id int `json:"id"`
-	}{Id: id})
+	tasks, err := TaskServiceInstance.ListTrash(r.Context(), filter)
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
+	writeJSON(w, http.StatusOK, tasks)
+}
 
-	_, err = w.Write(responseBody)
+// PurgeTask обрабатывает запрос на окончательное удаление задачи из корзины
+func PurgeTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.PurgeTask(r.Context(), taskID(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetTask обрабатывает запрос на получение задачи по ID
+// EmptyTrash обрабатывает запрос на очистку корзины и возвращает количество удаленных задач
+func EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	purged, err := TaskServiceInstance.EmptyTrash(r.Context())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Purged int64 `json:"purged"`
+	}{Purged: purged})
+}
+
+// PostTask обрабатывает POST запрос для создания задачи
+func PostTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	task, err := taskFromRequestBody(w, r)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	id, err := TaskServiceInstance.CreateTask(r.Context(), task)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", TasksPath, id))
+	writeJSON(w, http.StatusCreated, struct {
+		Id int `json:"id"`
+	}{Id: id})
+}
+
+// GetTask обрабатывает запрос на получение задачи по ID.
+// Версия задачи возвращается в ETag; если она совпадает с If-None-Match, ответ 304 без тела.
 func GetTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	// Получаем значение параметра "id" из URL запроса
-	id := r.URL.Query().Get("id")
 	// Получаем задачу по ID с помощью сервиса TaskServiceInstance
-	task, err := TaskServiceInstance.GetTask(id)
+	task, err := TaskServiceInstance.GetTask(r.Context(), taskID(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(task.Version))
+	if ifMatch := r.Header.Get("If-Match"); len(ifMatch) > 0 && !matchETag(ifMatch, task.Version, false) {
+		writeErrorAndRespond(w, r, taskerror.ErrPreconditionFailed)
+		return
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); len(ifNoneMatch) > 0 && matchETag(ifNoneMatch, task.Version, true) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	// Преобразуем полученную задачу в формат JSON и отправляем клиенту
-	response, err := json.Marshal(task)
+	writeJSON(w, http.StatusOK, task)
+}
+
+// GetTaskTree обрабатывает запрос задачи со всеми подзадачами любой вложенности
+func GetTaskTree(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	tree, err := TaskServiceInstance.GetTaskTree(r.Context(), taskID(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
-	w.Write(response)
+	writeJSON(w, http.StatusOK, tree)
 }
 
-// GetALLTasks обрабатывает запрос на получение списка задач
+// GetALLTasks обрабатывает запрос на получение списка задач.
+// Параметры запроса:
+//   - status - статус задачи, можно указать несколько раз или через запятую;
+//   - date - дата задачи, date_from и date_to - диапазон дат включительно;
+//   - title, description - подстрока заголовка или описания;
+//   - tag - тег задачи, можно указать несколько раз или через запятую;
+//     tag_match=any (по умолчанию) выбирает задачи с любым из тегов, tag_match=all - со всеми;
+//   - q (или search) - полнотекстовый поиск: слова, "фразы" и префиксы вида слово*,
+//     а также дата в формате 02.01.2006;
+//   - sort - поле сортировки (date, due, priority, position, title, status, id, а при поиске и relevance),
+//     префикс "-" или order=desc задают обратный порядок;
+//   - limit - размер страницы, cursor - значение next_cursor предыдущей страницы.
 func GetALLTasks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	var tasks *model.TaskList
-	var err error
-	// Если параметр "search" не указан, получаем все задачи, иначе ищем задачи по запросу
-
-	tasks, err = TaskServiceInstance.GetTasks()
+	filter, err := taskFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
 
+	tasks, err := TaskServiceInstance.ListTasks(r.Context(), filter)
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
 	// Преобразуем список задач в формат JSON и отправляем клиенту
-	response, err := json.Marshal(tasks)
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// GetOverdueTasks обрабатывает запрос списка просроченных задач.
+// Принимает те же параметры, что и GetALLTasks, по умолчанию задачи упорядочены по приоритету и сроку.
+func GetOverdueTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	filter, err := taskFilterFromQuery(r.URL.Query())
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
 
-	w.Write(response)
+	tasks, err := TaskServiceInstance.ListOverdue(r.Context(), filter)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
 }
 
-func GetTasksByStatus(w http.ResponseWriter, r *http.Request) {
+// taskFilterFromQuery собирает параметры выборки списка задач из строки запроса
+func taskFilterFromQuery(query url.Values) (model.TaskFilter, error) {
+	filter := model.TaskFilter{
+		DateFrom:    query.Get("date_from"),
+		DateTo:      query.Get("date_to"),
+		Title:       query.Get("title"),
+		Description: query.Get("description"),
+		Search:      query.Get("q"),
+		Sort:        query.Get("sort"),
+		Cursor:      query.Get("cursor"),
+	}
+	if len(filter.Search) == 0 {
+		filter.Search = query.Get("search")
+	}
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); len(status) > 0 {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); len(tag) > 0 {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	switch strings.ToLower(query.Get("tag_match")) {
+	case "", "any":
+	case "all":
+		filter.AllTags = true
+	default:
+		return filter, taskerror.ErrInvalidTagMatch
+	}
+	if date := query.Get("date"); len(date) > 0 {
+		filter.DateFrom, filter.DateTo = date, date
+	}
+	if strings.HasPrefix(filter.Sort, "-") {
+		filter.Sort, filter.Desc = filter.Sort[1:], true
+	}
+	switch strings.ToLower(query.Get("order")) {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, taskerror.ErrInvalidOrder
+	}
+	if limit := query.Get("limit"); len(limit) > 0 {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return filter, taskerror.ErrInvalidLimit
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+// PutTask обрабатывает запрос на полное обновление задачи и возвращает обновленную задачу.
+// Идентификатор берется из пути, а для устаревшего маршрута - из тела запроса.
+func PutTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	var tasks *model.TaskList
-	var err error
-	// Если параметр "search" не указан, получаем все задачи, иначе ищем задачи по запросу
+	task, err := taskFromRequestBody(w, r)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
 
-	status := r.URL.Query().Get("status")
-	tasks, err = TaskServiceInstance.GetTasksByStatus(status)
+	if id := chi.URLParam(r, "taskId"); len(id) > 0 {
+		pathID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			writeErrorAndRespond(w, r, taskerror.ErrInvalidID)
+			return
+		}
+		if task.Id != 0 && task.Id != pathID {
+			writeErrorAndRespond(w, r, taskerror.ErrIDMismatch)
+			return
+		}
+		task.Id = pathID
+	}
 
+	err = TaskServiceInstance.UpdateTask(r.Context(), task, precondition(r))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
-	// Преобразуем список задач в формат JSON и отправляем клиенту
-	response, err := json.Marshal(tasks)
+
+	updated, err := TaskServiceInstance.GetTask(r.Context(), strconv.FormatInt(task.Id, 10))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
-
-	w.Write(response)
+	w.Header().Set("ETag", etag(updated.Version))
+	writeJSON(w, http.StatusOK, updated)
 }
 
-func GetTasksByDateAndStatus(w http.ResponseWriter, r *http.Request) {
+// PatchTask обрабатывает запрос на частичное обновление задачи и возвращает обновленную задачу.
+// Тело запроса - JSON Merge Patch (RFC 7396) или JSON Patch (RFC 6902), формат задается Content-Type.
+func PatchTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
 
-	var tasks *model.TaskList
-	var err error
-	// Если параметр "search" не указан, получаем все задачи, иначе ищем задачи по запросу
-	date := r.URL.Query().Get("date")
-	status := r.URL.Query().Get("status")
-	tasks, err = TaskServiceInstance.GetTasksByDateAndStatus(status, date)
-
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, settings.RequestBodyLimit))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, taskerror.JSONError(err))
 		return
 	}
-	// Преобразуем список задач в формат JSON и отправляем клиенту
-	response, err := json.Marshal(tasks)
+	p, err := patch.Parse(mediaType, body)
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusInternalServerError, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
 
-	w.Write(response)
+	updated, err := TaskServiceInstance.PatchTask(r.Context(), taskID(r), p, precondition(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(updated.Version))
+	writeJSON(w, http.StatusOK, updated)
 }
 
-func PutTask(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+// GetNextDate обрабатывает запрос на вычисление следующей даты повторяющейся задачи.
+// Параметры: now (по умолчанию сегодня), date и repeat. Отвечает датой в виде текста.
+func GetNextDate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	now := time.Now()
+	if param := query.Get("now"); len(param) > 0 {
+		var err error
+		now, err = time.Parse(settings.DateFormat, param)
+		if err != nil {
+			writeErrorAndRespond(w, r, taskerror.ErrInvalidDate)
+			return
+		}
+	}
 
-	task, err := taskFromRequestBody(r)
+	next, err := task.NextDate(now, query.Get("date"), query.Get("repeat"))
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		writeErrorAndRespond(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	w.Write([]byte(next))
+}
 
-	err = TaskServiceInstance.UpdateTask(task)
+// writeJSON сериализует значение в JSON и отправляет его с указанным кодом состояния
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	response, err := json.Marshal(v)
 	if err != nil {
-		writeErrorAndRespond(w, http.StatusBadRequest, err)
+		log.Printf("marshal response: %v", err)
+		writeProblem(w, taskerror.NewProblem(taskerror.Internal(err), ""))
 		return
 	}
-	w.Write([]byte("{}"))
+	w.WriteHeader(statusCode)
+	w.Write(response)
 }
 
-// writeErrorAndRespond пишет ошибку в ответ и устанавливает соответствующий код состояния
-func writeErrorAndRespond(w http.ResponseWriter, statusCode int, err error) {
-	w.WriteHeader(statusCode)
-	w.Write(taskerror.MarshalError(err))
+// legacyErrorsKey ключ контекста запроса к устаревшему маршруту
+type legacyErrorsKey struct{}
+
+// LegacyErrors помечает запросы к устаревшим маршрутам: ошибки для них
+// возвращаются в прежнем формате {"error": "..."} вместо application/problem+json
+func LegacyErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), legacyErrorsKey{}, true)))
+	})
+}
+
+// writeErrorAndRespond пишет ошибку в ответ с кодом состояния, соответствующим ее категории.
+// Внутренние ошибки записываются в журнал, клиент получает только общее описание.
+func writeErrorAndRespond(w http.ResponseWriter, r *http.Request, err error) {
+	problem := taskerror.NewProblem(err, r.URL.Path)
+	if problem.Status == http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	if legacy, _ := r.Context().Value(legacyErrorsKey{}).(bool); legacy {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(problem.Status)
+		w.Write(taskerror.MarshalError(err))
+		return
+	}
+	writeProblem(w, problem)
+}
+
+// writeProblem отправляет описание ошибки в формате application/problem+json
+func writeProblem(w http.ResponseWriter, problem taskerror.Problem) {
+	response, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", taskerror.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(response)
 }
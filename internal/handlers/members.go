@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// InvitationsPath базовый путь ресурса приглашений в проекты в API v1
+const InvitationsPath = "/api/v1/invitations"
+
+// roleInput тело запроса на приглашение в проект и назначение роли участнику
+type roleInput struct {
+	Login string `json:"login"`
+	Role  string `json:"role"`
+}
+
+// GetProjectMembers обрабатывает запрос списка владельца и участников проекта
+func GetProjectMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	members, err := TaskServiceInstance.ListMembers(r.Context(), projectID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// PutProjectMember обрабатывает запрос назначения роли участнику проекта {userId}: {"role": "editor"}
+func PutProjectMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var input roleInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	member, err := TaskServiceInstance.UpdateMember(r.Context(), projectID(r), chi.URLParam(r, "userId"),
+		model.ProjectMember{Role: input.Role})
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, member)
+}
+
+// DeleteProjectMember обрабатывает запрос исключения участника {userId} из проекта или выхода из него
+func DeleteProjectMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.RemoveMember(r.Context(), projectID(r), chi.URLParam(r, "userId"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostProjectInvitation обрабатывает запрос приглашения пользователя в проект: {"login": "bob", "role": "viewer"}
+func PostProjectInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var input roleInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	id, err := TaskServiceInstance.InviteMember(r.Context(), projectID(r), model.Invitation{Login: input.Login, Role: input.Role})
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", InvitationsPath, id))
+	writeJSON(w, http.StatusCreated, struct {
+		Id int64 `json:"id"`
+	}{Id: id})
+}
+
+// GetProjectInvitations обрабатывает запрос списка приглашений в проект
+func GetProjectInvitations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	invitations, err := TaskServiceInstance.ListProjectInvitations(r.Context(), projectID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// DeleteProjectInvitation обрабатывает запрос отзыва приглашения {invitationId} в проект
+func DeleteProjectInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.RevokeInvitation(r.Context(), projectID(r), invitationID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invitationID возвращает идентификатор приглашения из параметра пути {invitationId}
+func invitationID(r *http.Request) string {
+	return chi.URLParam(r, "invitationId")
+}
+
+// GetInvitations обрабатывает запрос списка приглашений, адресованных пользователю
+func GetInvitations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	invitations, err := TaskServiceInstance.ListInvitations(r.Context())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// GetInvitation обрабатывает запрос приглашения по ID
+func GetInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	invitation, err := TaskServiceInstance.GetInvitation(r.Context(), invitationID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invitation)
+}
+
+// AcceptInvitation обрабатывает запрос принятия приглашения в проект
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := TaskServiceInstance.AcceptInvitation(r.Context(), invitationID(r)); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeclineInvitation обрабатывает запрос отказа от приглашения в проект
+func DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := TaskServiceInstance.DeclineInvitation(r.Context(), invitationID(r)); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// ProjectsPath базовый путь ресурса проектов в API v1
+const ProjectsPath = "/api/v1/projects"
+
+// projectInput тело запроса на создание и изменение проекта
+type projectInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// projectFromRequestBody извлекает проект из тела запроса
+func projectFromRequestBody(w http.ResponseWriter, r *http.Request) (model.Project, error) {
+	var input projectInput
+	err := decodeJSON(w, r, &input)
+	return model.Project{Name: input.Name, Description: input.Description}, err
+}
+
+// projectID возвращает идентификатор проекта из параметра пути {projectId}
+func projectID(r *http.Request) string {
+	return chi.URLParam(r, "projectId")
+}
+
+// GetProjects обрабатывает запрос списка проектов.
+// Параметр archived=true выбирает архивные проекты вместо действующих.
+func GetProjects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var archived bool
+	if param := r.URL.Query().Get("archived"); len(param) > 0 {
+		var err error
+		if archived, err = strconv.ParseBool(param); err != nil {
+			writeErrorAndRespond(w, r, taskerror.ErrInvalidArchived)
+			return
+		}
+	}
+
+	projects, err := TaskServiceInstance.ListProjects(r.Context(), archived)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// PostProject обрабатывает запрос на создание проекта
+func PostProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	project, err := projectFromRequestBody(w, r)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	id, err := TaskServiceInstance.CreateProject(r.Context(), project)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", ProjectsPath, id))
+	writeJSON(w, http.StatusCreated, struct {
+		Id int64 `json:"id"`
+	}{Id: id})
+}
+
+// GetProject обрабатывает запрос проекта по ID
+func GetProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	project, err := TaskServiceInstance.GetProject(r.Context(), projectID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, project)
+}
+
+// PutProject обрабатывает запрос на изменение имени и описания проекта и возвращает обновленный проект
+func PutProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	project, err := projectFromRequestBody(w, r)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	updated, err := TaskServiceInstance.UpdateProject(r.Context(), projectID(r), project)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteProject обрабатывает запрос на удаление проекта без задач
+func DeleteProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.DeleteProject(r.Context(), projectID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ArchiveProject обрабатывает запрос на отправку проекта с задачами в архив
+func ArchiveProject(w http.ResponseWriter, r *http.Request) {
+	archiveProject(w, r, true)
+}
+
+// UnarchiveProject обрабатывает запрос на возврат проекта из архива
+func UnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	archiveProject(w, r, false)
+}
+
+// archiveProject отправляет проект в архив или возвращает из архива
+func archiveProject(w http.ResponseWriter, r *http.Request, archived bool) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.ArchiveProject(r.Context(), projectID(r), archived)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetProjectTasks обрабатывает запрос списка задач проекта, в том числе архивного.
+// Принимает те же параметры, что и GetALLTasks, по умолчанию задачи идут в порядке проекта.
+func GetProjectTasks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	filter, err := taskFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	tasks, err := TaskServiceInstance.ListProjectTasks(r.Context(), projectID(r), filter)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// PutProjectOrder обрабатывает запрос на упорядочивание задач проекта.
+// Задачи из тела запроса встают в начало проекта в указанном порядке, остальные следуют за ними.
+func PutProjectOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var input struct {
+		Tasks []int64 `json:"tasks"`
+	}
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	err := TaskServiceInstance.ReorderProject(r.Context(), projectID(r), input.Tasks)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveTask обрабатывает запрос переноса задачи {taskId} вплотную перед задачей before или после задачи after
+// того же проекта
+func MoveTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var move model.TaskMove
+	if err := decodeJSON(w, r, &move); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	err := TaskServiceInstance.MoveTask(r.Context(), taskID(r), move)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
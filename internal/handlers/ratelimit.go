@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/ratelimit"
+)
+
+// RateLimiterInstance ограничивает частоту запросов клиентов. Если он не задан, частота не ограничивается.
+var RateLimiterInstance *ratelimit.Limiter
+
+// RateLimit ограничивает частоту запросов клиентов к группе маршрутов group. Клиент - ключ API,
+// пользователь из токена или, для запросов без них, адрес клиента, поэтому в группах с RequireAuth
+// RateLimit используется после него, а перед ним RateLimit("ip") ограничивает адрес, в том числе запросы
+// с неверным токеном или ключом. Ответ содержит заголовки RateLimit-Limit, RateLimit-Remaining
+// и RateLimit-Reset, а превысивший ограничение клиент получает 429 с заголовком Retry-After.
+// Если хранилище корзин недоступно, запрос выполняется без ограничения.
+func RateLimit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if RateLimiterInstance == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			client, ok := r.Context().Value(clientKey{}).(string)
+			if !ok {
+				client = "ip:" + clientIP(r)
+			}
+
+			result, err := RateLimiterInstance.Allow(r.Context(), group, client)
+			if err != nil {
+				log.Printf("rate limit %s %s: %v", group, client, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if result.Limit > 0 {
+				limit := RateLimiterInstance.Limit(group)
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+				w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+				w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, ceilSeconds(limit.Period)))
+			}
+			if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				writeErrorAndRespond(w, r, taskerror.ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds возвращает длительность в целых секундах с округлением вверх
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
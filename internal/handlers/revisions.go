@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetRevisions обрабатывает запрос списка сохраненных снимков задачи
+func GetRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	revisions, err := TaskServiceInstance.ListRevisions(r.Context(), taskID(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Revisions []model.TaskRevision `json:"revisions"`
+	}{Revisions: revisions})
+}
+
+// GetRevision обрабатывает запрос снимка задачи с номером из параметра пути {revision}
+func GetRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	revision, err := TaskServiceInstance.GetRevision(r.Context(), taskID(r), chi.URLParam(r, "revision"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, revision)
+}
+
+// GetRevertPreview обрабатывает запрос изменений, которые внесет возврат задачи к снимку {revision}
+func GetRevertPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	preview, err := TaskServiceInstance.PreviewRevert(r.Context(), taskID(r), chi.URLParam(r, "revision"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(preview.Version))
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// RevertTask обрабатывает запрос возврата задачи к снимку из параметра запроса revision
+// и возвращает задачу в новой версии
+func RevertTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	reverted, err := TaskServiceInstance.RevertTask(r.Context(), taskID(r), r.URL.Query().Get("revision"), precondition(r))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(reverted.Version))
+	writeJSON(w, http.StatusOK, reverted)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// TagsPath базовый путь ресурса тегов в API v1
+const TagsPath = "/api/v1/tags"
+
+// tagInput тело запроса на создание и переименование тега
+type tagInput struct {
+	Name string `json:"name"`
+}
+
+// tagFromRequestBody извлекает тег из тела запроса
+func tagFromRequestBody(w http.ResponseWriter, r *http.Request) (model.Tag, error) {
+	var input tagInput
+	err := decodeJSON(w, r, &input)
+	return model.Tag{Name: input.Name}, err
+}
+
+// GetTags обрабатывает запрос списка тегов для автодополнения.
+// Параметры: prefix - начало имени тега, limit - наибольшее количество тегов.
+func GetTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	query := r.URL.Query()
+	var limit int
+	if param := query.Get("limit"); len(param) > 0 {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil {
+			writeErrorAndRespond(w, r, taskerror.ErrInvalidLimit)
+			return
+		}
+	}
+
+	tags, err := TaskServiceInstance.ListTags(r.Context(), query.Get("prefix"), limit)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// PostTag обрабатывает запрос на создание тега
+func PostTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	tag, err := tagFromRequestBody(w, r)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	id, err := TaskServiceInstance.CreateTag(r.Context(), tag)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", TagsPath, id))
+	writeJSON(w, http.StatusCreated, struct {
+		Id int64 `json:"id"`
+	}{Id: id})
+}
+
+// GetTag обрабатывает запрос тега по ID
+func GetTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	tag, err := TaskServiceInstance.GetTag(r.Context(), chi.URLParam(r, "tagId"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tag)
+}
+
+// PutTag обрабатывает запрос на переименование тега и возвращает обновленный тег
+func PutTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	tag, err := tagFromRequestBody(w, r)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	updated, err := TaskServiceInstance.RenameTag(r.Context(), chi.URLParam(r, "tagId"), tag)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteTag обрабатывает запрос на удаление тега
+func DeleteTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	err := TaskServiceInstance.DeleteTag(r.Context(), chi.URLParam(r, "tagId"))
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
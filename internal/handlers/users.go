@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// UsersPath базовый путь ресурса пользователей в API v1
+const UsersPath = "/api/v1/users"
+
+// PostUser обрабатывает запрос регистрации пользователя
+func PostUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var registration model.Registration
+	if err := decodeJSON(w, r, &registration); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	id, err := TaskServiceInstance.RegisterUser(r.Context(), registration)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", UsersPath+"/me")
+	writeJSON(w, http.StatusCreated, struct {
+		Id int64 `json:"id"`
+	}{Id: id})
+}
+
+// LoginUser обрабатывает запрос входа по логину и паролю: возвращает токен пользователя {"token": "..."}
+// и сохраняет его в cookie token на срок действия токена
+func LoginUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var credentials model.Credentials
+	if err := decodeJSON(w, r, &credentials); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	if AuthInstance == nil {
+		writeErrorAndRespond(w, r, taskerror.ErrUnauthorized)
+		return
+	}
+	user, err := TaskServiceInstance.Authenticate(r.Context(), credentials)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	token, expires, err := AuthInstance.Issue(*user)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	respondToken(w, token, expires)
+}
+
+// GetProfile обрабатывает запрос профиля пользователя, от имени которого выполняется запрос
+func GetProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	user, err := TaskServiceInstance.GetProfile(r.Context())
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// PutProfile обрабатывает запрос изменения имени и пароля пользователя, от имени которого выполняется запрос
+func PutProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var update model.ProfileUpdate
+	if err := decodeJSON(w, r, &update); err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+
+	user, err := TaskServiceInstance.UpdateProfile(r.Context(), update)
+	if err != nil {
+		writeErrorAndRespond(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
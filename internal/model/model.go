@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Task struct {
 	Id int64 `json:"id"`
 
@@ -9,10 +11,434 @@ type Task struct {
 
 	Date string `json:"date,omitempty"`
 
+	// ProjectId проект задачи. При создании 0 означает Входящие, при обновлении - проект без изменений.
+	ProjectId int64 `json:"project_id,omitempty"`
+
+	// OwnerId пользователь, которому принадлежит задача; заполняется при чтении, в запросах не учитывается
+	OwnerId int64 `json:"owner_id,omitempty"`
+
+	// ParentId родительская задача подзадачи, nil - задача верхнего уровня.
+	// В запросах на изменение отсутствие поля оставляет родителя без изменений, а 0 делает задачу задачей верхнего уровня.
+	ParentId *int64 `json:"parent_id,omitempty"`
+
+	// BlockedBy ID невыполненных задач, которые блокируют эту задачу.
+	// Blocked вычисляется при чтении: задача в статусе blocked или ее блокирует невыполненная задача.
+	BlockedBy []int64 `json:"blocked_by,omitempty"`
+	Blocked   bool    `json:"blocked,omitempty"`
+
+	// Progress выполнение подзадач любой вложенности, заполняется только у задач с подзадачами
+	Progress *TaskProgress `json:"progress,omitempty"`
+
+	// SortRank ранг задачи, задающий ее порядок внутри проекта: задачи упорядочены по рангу побайтово
+	SortRank string `json:"-"`
+
+	// Time необязательное время срока выполнения в формате HH:MM; без него срок - конец дня Date
+	Time string `json:"time,omitempty"`
+
+	// Priority приоритет от 1 (P1, наивысший) до 4 (P4, по умолчанию)
+	Priority int `json:"priority,omitempty"`
+
 	Status string `json:"status,omitempty"`
+
+	// Repeat правило повторения задачи, например "d 7", "w 1,5", "m 1,-1" или "y"
+	Repeat string `json:"repeat,omitempty"`
+
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// CompletedAt время перевода задачи в статус done
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// Tags имена тегов задачи по алфавиту. В запросах на изменение отсутствие поля
+	// оставляет теги без изменений, а пустой список снимает все теги.
+	Tags []string `json:"tags,omitempty"`
+
+	// Version номер версии задачи, увеличивается при каждом изменении; передается в ETag
+	Version int64 `json:"version,omitempty"`
+
+	// DeletedAt время переноса задачи в корзину, заполняется только для задач из корзины
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Overdue вычисляется при чтении: срок прошел, а задача не выполнена и не отменена
+	Overdue bool `json:"overdue,omitempty"`
+
+	// Rank и Highlights заполняются только в результатах полнотекстового поиска
+	Rank float64 `json:"rank,omitempty"`
+
+	Highlights *TaskHighlights `json:"highlights,omitempty"`
+}
+
+// Статусы задачи
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusDone       = "done"
+	StatusCancelled  = "cancelled"
+)
+
+// Приоритеты задачи: P1 - наивысший, P4 - приоритет по умолчанию
+const (
+	PriorityHighest = 1
+	PriorityLowest  = 4
+)
+
+// TaskProgress выполнение подзадач задачи. Отмененные подзадачи не учитываются.
+type TaskProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+
+	// Percent доля выполненных подзадач в процентах, округленная вниз
+	Percent int64 `json:"percent"`
+}
+
+// TaskMove место, куда переносится задача: вплотную перед задачей Before или после задачи After того же проекта
+type TaskMove struct {
+	Before int64 `json:"before,omitempty"`
+	After  int64 `json:"after,omitempty"`
+}
+
+// TaskTree задача со всеми подзадачами, упорядоченными так же, как задачи проекта
+type TaskTree struct {
+	Task
+
+	Subtasks []TaskTree `json:"subtasks"`
+}
+
+// TaskHighlights содержит фрагменты задачи, в которых совпадения с поисковым запросом
+// обрамлены тегами <mark>. Остальной HTML в фрагментах экранирован.
+type TaskHighlights struct {
+	Title string `json:"title,omitempty"`
+
+	Description string `json:"description,omitempty"`
 }
 
 // TaskList Структура представляет собой список задач
 type TaskList struct {
 	Tasks []Task `json:"tasks"`
+
+	// NextCursor курсор следующей страницы, пустой на последней странице
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TaskFilter описывает параметры выборки списка задач.
+// Пустые поля не участвуют в фильтрации.
+type TaskFilter struct {
+	// Statuses задачи с любым из перечисленных статусов
+	Statuses []string
+
+	// DateFrom и DateTo границы диапазона дат включительно в формате YYYYMMDD
+	DateFrom string
+	DateTo   string
+
+	// Title и Description подстроки заголовка и описания
+	Title       string
+	Description string
+
+	// Search полнотекстовый запрос по заголовку и описанию
+	Search string
+
+	// Sort поле сортировки, Desc - сортировка по убыванию.
+	// Сортировка relevance доступна только вместе с Search.
+	Sort string
+	Desc bool
+
+	// Limit размер страницы, Cursor - курсор, полученный с предыдущей страницей
+	Limit  int
+	Cursor string
+
+	// Tags задачи с тегами: с любым из перечисленных или, если AllTags, со всеми сразу
+	Tags    []string
+	AllTags bool
+
+	// ProjectId задачи одного проекта, в том числе архивного.
+	// Без проекта выбираются задачи всех проектов, кроме архивных.
+	ProjectId int64
+
+	// Trash выбирает задачи из корзины вместо обычных задач
+	Trash bool
+
+	// Actionable выбирает задачи, которые можно начать: не выполненные, не отмененные,
+	// не в статусе blocked и без невыполненных блокирующих задач
+	Actionable bool
+
+	// Overdue выбирает просроченные на момент Now задачи
+	Overdue bool
+	Now     time.Time
+}
+
+// Действия журнала изменений задач
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDone    = "done"
+	ActionReopen  = "reopen"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+	ActionRevert  = "revert"
+)
+
+// TaskEvent запись журнала изменений задачи
+type TaskEvent struct {
+	Id     int64  `json:"id"`
+	TaskId int64  `json:"task_id"`
+	Action string `json:"action"`
+
+	// Actor инициатор изменения
+	Actor string `json:"actor,omitempty"`
+
+	// Changes значения измененных полей до и после изменения
+	Changes map[string]FieldChange `json:"changes"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FieldChange значение поля задачи до и после изменения; null - поле было или стало пустым
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// TaskEventList страница записей журнала изменений
+type TaskEventList struct {
+	Events []TaskEvent `json:"events"`
+
+	// NextCursor курсор следующей страницы, пустой на последней странице
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// EventFilter описывает параметры выборки журнала изменений.
+// Записи возвращаются от новых к старым, пустые поля не участвуют в фильтрации.
+type EventFilter struct {
+	// TaskId записи одной задачи
+	TaskId int64
+
+	// Actions записи с любым из перечисленных действий
+	Actions []string
+
+	// From и To границы времени записи: From включительно, To - исключая
+	From time.Time
+	To   time.Time
+
+	// Limit размер страницы, Cursor - курсор, полученный с предыдущей страницей
+	Limit  int
+	Cursor string
+}
+
+// TaskRevision сохраненный снимок полей задачи после изменения.
+// Номер снимка совпадает с версией задачи, которую получила задача после изменения.
+type TaskRevision struct {
+	Revision int64  `json:"revision"`
+	Action   string `json:"action"`
+
+	// Task состояние задачи в этом снимке
+	Task Task `json:"task"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevertPreview изменения, которые внесет возврат задачи к снимку Revision
+type RevertPreview struct {
+	Revision int64 `json:"revision"`
+
+	// Version текущая версия задачи, с которой сравнивается снимок
+	Version int64 `json:"version"`
+
+	// Changes поля, которые изменятся: from - текущее значение, to - значение из снимка
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// Tag тег задач
+type Tag struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+
+	// Count количество задач с тегом, не считая задач из корзины
+	Count int64 `json:"count"`
+}
+
+// TagList список тегов
+type TagList struct {
+	Tags []Tag `json:"tags"`
+}
+
+// InboxProjectId идентификатор проекта Входящие, в который попадают задачи без проекта.
+// Входящие нельзя удалить или отправить в архив.
+const InboxProjectId = 1
+
+// Project проект, объединяющий задачи
+type Project struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// TaskCount количество задач проекта, не считая задач из корзины
+	TaskCount int64 `json:"task_count"`
+
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// ArchivedAt время отправки проекта в архив; задачи архивного проекта не попадают в общие списки
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// OwnerId владелец проекта; у общих Входящих владельца нет
+	OwnerId int64 `json:"owner_id,omitempty"`
+	// Role роль текущего пользователя в проекте; во Входящих пользователь работает только со своими задачами
+	Role string `json:"role,omitempty"`
+}
+
+// ProjectList список проектов
+type ProjectList struct {
+	Projects []Project `json:"projects"`
+}
+
+// Dependency связь задач: BlockerId блокирует TaskId, пока не будет выполнена или отменена
+type Dependency struct {
+	BlockerId int64 `json:"blocker_id"`
+	TaskId    int64 `json:"task_id"`
+}
+
+// TaskDependencies задачи, связанные с задачей зависимостями
+type TaskDependencies struct {
+	// BlockedBy задачи, которые блокируют задачу, в том числе уже выполненные
+	BlockedBy []Task `json:"blocked_by"`
+
+	// Blocks задачи, которые блокирует задача
+	Blocks []Task `json:"blocks"`
+}
+
+// DefaultUserId идентификатор пользователя по умолчанию. Ему принадлежат задачи, созданные до появления
+// пользователей, и от его имени работают запросы без входа и после входа по паролю TODO_PASSWORD.
+const DefaultUserId = 1
+
+// User пользователь API. Задачи, проекты и теги пользователя видны только ему
+// и участникам его проектов, см. ProjectMember.
+type User struct {
+	Id    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name,omitempty"`
+
+	// PasswordHash хеш пароля bcrypt; пустой у пользователя по умолчанию, который не входит по логину
+	PasswordHash string `json:"-"`
+
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Registration данные для регистрации пользователя
+type Registration struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+// Credentials логин и пароль для входа
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// ProfileUpdate изменение профиля пользователя. Пустой Password оставляет пароль без изменений,
+// а для смены пароля нужен текущий пароль CurrentPassword.
+type ProfileUpdate struct {
+	Name            string `json:"name"`
+	Password        string `json:"password"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// Роли пользователей в проектах. Каждая следующая роль разрешает все, что и предыдущая.
+const (
+	// RoleViewer читает задачи проекта
+	RoleViewer = "viewer"
+	// RoleEditor создает, изменяет и удаляет задачи проекта
+	RoleEditor = "editor"
+	// RoleAdmin изменяет проект и приглашает участников, назначает им роли и исключает их
+	RoleAdmin = "admin"
+	// RoleOwner владелец проекта; только он может удалить проект
+	RoleOwner = "owner"
+)
+
+// ProjectMember участник проекта и его роль. Владелец проекта входит в список участников с ролью owner.
+type ProjectMember struct {
+	UserId int64  `json:"user_id"`
+	Login  string `json:"login"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role"`
+
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ProjectMemberList список участников проекта
+type ProjectMemberList struct {
+	Members []ProjectMember `json:"members"`
+}
+
+// Invitation приглашение пользователя Login в проект с ролью Role. Приглашенный становится участником проекта,
+// когда примет приглашение.
+type Invitation struct {
+	Id          int64  `json:"id"`
+	ProjectId   int64  `json:"project_id"`
+	ProjectName string `json:"project_name,omitempty"`
+	UserId      int64  `json:"-"`
+	Login       string `json:"login"`
+	Role        string `json:"role"`
+
+	// InvitedBy логин пользователя, который пригласил
+	InvitedBy string     `json:"invited_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// InvitationList список приглашений
+type InvitationList struct {
+	Invitations []Invitation `json:"invitations"`
+}
+
+// Разрешения ключа API. tasks:read разрешает запросы чтения GET и HEAD к задачам, tasks:write - изменяющие
+// их запросы. Для чтения и изменения нужны оба разрешения. К другим ресурсам ключ доступа не дает.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+)
+
+// APIKey ключ API пользователя для скриптов и интеграций. Сам ключ Key возвращается только при создании,
+// хранится лишь его хеш, а в списке ключей ключ можно узнать по началу Prefix.
+type APIKey struct {
+	Id     int64    `json:"id"`
+	UserId int64    `json:"-"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	Key    string   `json:"key,omitempty"`
+
+	// KeyHash хеш SHA-256 ключа, по которому ключ находится при проверке запроса
+	KeyHash string `json:"-"`
+
+	// ExpiresAt время, после которого ключ перестает действовать; без него ключ бессрочный
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// Allows проверяет, есть ли у ключа разрешение scope
+func (key APIKey) Allows(scope string) bool {
+	for _, current := range key.Scopes {
+		if current == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyInput данные для создания ключа API. Без ExpiresAt ключ бессрочный.
+type APIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyList список ключей API пользователя
+type APIKeyList struct {
+	Keys []APIKey `json:"keys"`
 }
@@ -0,0 +1,355 @@
+// Package patch применяет частичные изменения к JSON-документам
+// в форматах JSON Merge Patch (RFC 7396) и JSON Patch (RFC 6902).
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ZnNr/todo-list/internal/error"
+)
+
+const (
+	// MergePatchType тип содержимого JSON Merge Patch
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType тип содержимого JSON Patch
+	JSONPatchType = "application/json-patch+json"
+)
+
+// Patch изменение JSON-документа
+type Patch interface {
+	// Apply возвращает измененную копию документа doc
+	Apply(doc []byte) ([]byte, error)
+}
+
+// Parse разбирает тело запроса в формате, заданном типом содержимого contentType
+func Parse(contentType string, data []byte) (Patch, error) {
+	switch contentType {
+	case MergePatchType:
+		return ParseMergePatch(data)
+	case JSONPatchType:
+		return ParseJSONPatch(data)
+	}
+	return nil, taskerror.ErrUnsupportedPatch
+}
+
+// MergePatch документ JSON Merge Patch: объект, поля которого заменяют поля
+// документа, а null удаляет поле
+type MergePatch struct {
+	value any
+}
+
+// ParseMergePatch разбирает документ JSON Merge Patch
+func ParseMergePatch(data []byte) (MergePatch, error) {
+	var p MergePatch
+	if err := json.Unmarshal(data, &p.value); err != nil {
+		return p, taskerror.ErrInvalidPatch.Wrap(err)
+	}
+	return p, nil
+}
+
+func (p MergePatch) Apply(doc []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p.value))
+}
+
+// mergePatch применяет patch к target по алгоритму MergePatch из RFC 7396
+func mergePatch(target, patch any) any {
+	fields, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]any)
+	if !ok {
+		result = map[string]any{}
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(result, name)
+		} else {
+			result[name] = mergePatch(result[name], value)
+		}
+	}
+	return result
+}
+
+// Operation одна операция JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch документ JSON Patch: операции, применяемые по порядку.
+// Если хотя бы одна операция не выполнена, документ не изменяется.
+type JSONPatch []Operation
+
+// ParseJSONPatch разбирает документ JSON Patch и проверяет операции
+func ParseJSONPatch(data []byte) (JSONPatch, error) {
+	var p JSONPatch
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, taskerror.ErrInvalidPatch.Wrap(err)
+	}
+	for i, op := range p {
+		if err := op.validate(); err != nil {
+			return nil, taskerror.ErrInvalidPatch.Wrap(fmt.Errorf("operation %d: %w", i, err))
+		}
+	}
+	return p, nil
+}
+
+// validate проверяет наличие обязательных членов операции
+func (op Operation) validate() error {
+	if _, err := parsePointer(op.Path); err != nil {
+		return err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return fmt.Errorf("%s requires value", op.Op)
+		}
+	case "move", "copy":
+		if _, err := parsePointer(op.From); err != nil {
+			return err
+		}
+		if op.Op == "move" && strings.HasPrefix(op.Path, op.From+"/") {
+			return fmt.Errorf("cannot move %q into its own child", op.From)
+		}
+	case "remove":
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+	return nil
+}
+
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		var err error
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// apply выполняет операцию над документом и возвращает его новый корень
+func (op Operation) apply(doc any) (any, error) {
+	path, _ := parsePointer(op.Path)
+	from, _ := parsePointer(op.From)
+
+	var value any
+	if op.Value != nil {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, taskerror.ErrInvalidPatch.Wrap(err)
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		return replace(doc, path, value)
+	case "move":
+		moved, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, moved)
+	case "copy":
+		copied, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(copied))
+	case "test":
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, value) {
+			return nil, taskerror.ErrPatchTestFailed
+		}
+		return doc, nil
+	}
+	return nil, taskerror.ErrInvalidPatch
+}
+
+// pointer разобранный JSON Pointer (RFC 6901), пустой указатель обозначает весь документ
+type pointer []string
+
+// parsePointer разбирает JSON Pointer, раскрывая экранирование ~1 и ~0
+func parsePointer(s string) (pointer, error) {
+	if len(s) == 0 {
+		return pointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// get возвращает значение по указателю
+func get(doc any, path pointer) (any, error) {
+	for _, token := range path {
+		var err error
+		if doc, err = child(doc, token); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// child возвращает элемент объекта или массива
+func child(node any, token string) (any, error) {
+	switch node := node.(type) {
+	case map[string]any:
+		value, ok := node[token]
+		if !ok {
+			return nil, taskerror.ErrPatchPath
+		}
+		return value, nil
+	case []any:
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		return node[i], nil
+	}
+	return nil, taskerror.ErrPatchPath
+}
+
+// arrayIndex разбирает индекс массива без ведущих нулей и проверяет, что он не больше max
+func arrayIndex(token string, max int) (int, error) {
+	if len(token) > 1 && token[0] == '0' {
+		return 0, taskerror.ErrPatchPath
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max {
+		return 0, taskerror.ErrPatchPath
+	}
+	return i, nil
+}
+
+// modify спускается по указателю до родителя последнего элемента, заменяет родителя
+// результатом fn и возвращает новый корень документа
+func modify(doc any, path pointer, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	node, err := child(doc, path[0])
+	if err != nil {
+		return nil, err
+	}
+	if node, err = modify(node, path[1:], fn); err != nil {
+		return nil, err
+	}
+	switch parent := doc.(type) {
+	case map[string]any:
+		parent[path[0]] = node
+	case []any:
+		i, _ := arrayIndex(path[0], len(parent)-1)
+		parent[i] = node
+	}
+	return doc, nil
+}
+
+// add добавляет поле объекта или вставляет элемент массива; "-" означает конец массива
+func add(doc any, path pointer, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, func(parent any, token string) (any, error) {
+		switch parent := parent.(type) {
+		case map[string]any:
+			parent[token] = value
+			return parent, nil
+		case []any:
+			i := len(parent)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(parent)); err != nil {
+					return nil, err
+				}
+			}
+			parent = append(parent, nil)
+			copy(parent[i+1:], parent[i:])
+			parent[i] = value
+			return parent, nil
+		}
+		return nil, taskerror.ErrPatchPath
+	})
+}
+
+// remove удаляет существующее поле объекта или элемент массива
+func remove(doc any, path pointer) (any, error) {
+	if len(path) == 0 {
+		return nil, taskerror.ErrPatchPath
+	}
+	return modify(doc, path, func(parent any, token string) (any, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+		switch parent := parent.(type) {
+		case map[string]any:
+			delete(parent, token)
+			return parent, nil
+		case []any:
+			i, _ := arrayIndex(token, len(parent)-1)
+			return append(parent[:i], parent[i+1:]...), nil
+		}
+		return nil, taskerror.ErrPatchPath
+	})
+}
+
+// replace заменяет значение существующего поля объекта или элемента массива
+func replace(doc any, path pointer, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return modify(doc, path, func(parent any, token string) (any, error) {
+		if _, err := child(parent, token); err != nil {
+			return nil, err
+		}
+		switch parent := parent.(type) {
+		case map[string]any:
+			parent[token] = value
+			return parent, nil
+		case []any:
+			i, _ := arrayIndex(token, len(parent)-1)
+			parent[i] = value
+			return parent, nil
+		}
+		return nil, taskerror.ErrPatchPath
+	})
+}
+
+// deepCopy копирует значение, чтобы операция copy не связывала части документа
+func deepCopy(value any) any {
+	data, _ := json.Marshal(value)
+	var result any
+	_ = json.Unmarshal(data, &result)
+	return result
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval период удаления полных корзин из MemoryStore
+const sweepInterval = time.Minute
+
+// memoryBucket корзина MemoryStore вместе с ограничением, по которому видно, пополнилась ли она
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+// MemoryStore хранит корзины в памяти процесса. Полные корзины удаляются, поэтому память занимают
+// только клиенты, которые недавно делали запросы. Корзины не переживают перезапуск сервера
+// и не разделяются между его экземплярами.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore создает пустое хранилище корзин в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}}
+}
+
+// Take забирает токен из корзины key, создавая полную корзину для нового клиента
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+	bucket, ok := s.buckets[key]
+	if !ok || bucket.limit != limit {
+		bucket = &memoryBucket{Bucket: NewBucket(limit, now), limit: limit}
+		s.buckets[key] = bucket
+	}
+	return bucket.Take(limit, now), nil
+}
+
+// Len возвращает число хранимых корзин
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// sweep удаляет корзины, которые успели пополниться полностью: новая корзина клиента будет такой же
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if bucket.Full(bucket.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,150 @@
+// Package ratelimit ограничивает частоту запросов клиентов алгоритмом token bucket.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit ограничение частоты запросов: не больше Requests запросов за Period. Корзина клиента вмещает
+// Requests токенов и пополняется равномерно, поэтому клиент может сделать Requests запросов подряд,
+// а дальше - по одному запросу на каждую долю Period. Нулевое ограничение не ограничивает ничего.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled проверяет, ограничивает ли Limit частоту запросов
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// String возвращает ограничение в формате ParseLimit
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// rate скорость пополнения корзины в токенах в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// ParseLimit разбирает ограничение вида 600/m: число запросов и период s, m, h или длительность вроде 30s.
+// off и 0 отключают ограничение.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		return Limit{}, nil
+	}
+	count, period, found := strings.Cut(value, "/")
+	requests, err := strconv.Atoi(count)
+	if !found || err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/period, for example 600/m", value)
+	}
+	if period == "s" || period == "m" || period == "h" {
+		period = "1" + period
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit period %q, expected s, m, h or a duration", period)
+	}
+	return Limit{Requests: requests, Period: duration}, nil
+}
+
+// Result результат попытки выполнить запрос
+type Result struct {
+	Allowed bool
+	// Limit размер корзины, Remaining - сколько запросов еще можно сделать подряд
+	Limit     int
+	Remaining int
+	// RetryAfter через сколько станет доступен следующий запрос, если этот отклонен
+	RetryAfter time.Duration
+	// Reset через сколько корзина пополнится полностью
+	Reset time.Duration
+}
+
+// Bucket состояние корзины токенов клиента. Хранилища хранят корзины и изменяют их методом Take.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// NewBucket возвращает полную корзину для ограничения limit
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Requests), Updated: now}
+}
+
+// Take пополняет корзину за время с прошлого запроса и забирает токен, если он есть
+func (b *Bucket) Take(limit Limit, now time.Time) Result {
+	rate := limit.rate()
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Requests), b.Tokens+elapsed.Seconds()*rate)
+		b.Updated = now
+	}
+
+	result := Result{Limit: limit.Requests}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+	result.Remaining = int(b.Tokens)
+	result.Reset = seconds((float64(limit.Requests) - b.Tokens) / rate)
+	return result
+}
+
+// Full проверяет, пополнилась ли корзина полностью к моменту now: такую корзину можно не хранить
+func (b Bucket) Full(limit Limit, now time.Time) bool {
+	return b.Tokens+now.Sub(b.Updated).Seconds()*limit.rate() >= float64(limit.Requests)
+}
+
+// seconds переводит секунды в длительность
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store хранит корзины клиентов. Take должен изменять корзину атомарно, чтобы параллельные запросы
+// одного клиента не получили один и тот же токен. Хранилище, общее для нескольких экземпляров сервера,
+// например Redis, позволяет им ограничивать клиентов вместе.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Limiter ограничивает частоту запросов клиентов по группам маршрутов: у каждой группы свое ограничение
+// и свои корзины клиентов
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// NewLimiter создает Limiter с корзинами в store. limits - ограничения групп маршрутов,
+// ограничение с ключом "" действует для групп, которых нет в limits.
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// Limit возвращает ограничение группы маршрутов group
+func (l *Limiter) Limit(group string) Limit {
+	if limit, ok := l.limits[group]; ok {
+		return limit
+	}
+	return l.limits[""]
+}
+
+// Allow забирает токен из корзины клиента client в группе group. Без ограничения группы запрос разрешен всегда,
+// а Result.Limit равен 0.
+func (l *Limiter) Allow(ctx context.Context, group, client string) (Result, error) {
+	limit := l.Limit(group)
+	if !limit.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, group+"|"+client, limit, time.Now())
+}
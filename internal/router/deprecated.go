@@ -0,0 +1,39 @@
+package router
+
+import (
+	"net/http"
+)
+
+// deprecated помечает устаревшие маршруты заголовками Deprecation и Link
+// и сохраняет их прежний контракт: успешный ответ всегда имеет код 200,
+// а пустой ответ заменяется на "{}".
+func deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+			lw := &legacyWriter{ResponseWriter: w}
+			next.ServeHTTP(lw, r)
+			if lw.noContent {
+				w.Write([]byte("{}"))
+			}
+		})
+	}
+}
+
+// legacyWriter приводит коды 201 и 204 к коду 200
+type legacyWriter struct {
+	http.ResponseWriter
+	noContent bool
+}
+
+func (w *legacyWriter) WriteHeader(statusCode int) {
+	switch statusCode {
+	case http.StatusCreated:
+		statusCode = http.StatusOK
+	case http.StatusNoContent:
+		w.noContent = true
+		statusCode = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -8,25 +8,155 @@ import (
 	"net/http"
 )
 
-func StartServer() {
+// NewRouter создает маршрутизатор со всеми маршрутами API
+func NewRouter() http.Handler {
 
 	// Инициализация маршрутизатора.
 	r := chi.NewRouter()
-	// Установка маршрутов для обработки файлов и API.
+	r.Use(handlers.Actor) // Инициатор изменений для журнала
+
+	// Все маршруты, кроме регистрации, входа и вычисления даты, выполняются от имени пользователя из токена или ключа API;
+	// без токена - от имени пользователя по умолчанию, если не задан пароль TODO_PASSWORD.
+	// Ключи API принимаются только маршрутами задач, а учетную запись и участников проектов изменяют только после входа.
+	// Частота запросов ограничивается для каждой группы маршрутов отдельно, см. settings.RateLimits,
+	// а до проверки токена или ключа - еще и для адреса клиента, чтобы ограничить и подбор токенов и ключей.
+	// Ресурс задач API v1.
+	r.With(handlers.RateLimit("ip"), handlers.RequireTaskAuth, handlers.RateLimit("tasks")).Route(handlers.TasksPath, func(r chi.Router) {
+		r.Post("/", handlers.PostTask)                    // Создание задачи
+		r.Get("/", handlers.GetALLTasks)                  // Список задач
+		r.Get("/overdue", handlers.GetOverdueTasks)       // Просроченные задачи
+		r.Get("/actionable", handlers.GetActionableTasks) // Задачи, которые можно начать
+		r.Get("/plan", handlers.GetTaskPlan)              // Невыполненные задачи в порядке выполнения
+
+		r.Route("/{taskId}", func(r chi.Router) {
+			r.Get("/", handlers.GetTask)               // Получение конкретной задачи
+			r.Put("/", handlers.PutTask)               // Обновление задачи
+			r.Patch("/", handlers.PatchTask)           // Частичное обновление задачи
+			r.Delete("/", handlers.DeleteTask)         // Удаление задачи в корзину
+			r.Post("/done", handlers.DonePostTask)     // Отметка задачи как выполненной
+			r.Post("/reopen", handlers.ReopenTask)     // Возврат выполненной или отмененной задачи в работу
+			r.Post("/restore", handlers.RestoreTask)   // Возврат задачи из корзины
+			r.Get("/history", handlers.GetTaskHistory) // История изменений задачи
+			r.Get("/tree", handlers.GetTaskTree)       // Задача со всеми подзадачами
+			r.Post("/revert", handlers.RevertTask)     // Возврат задачи к сохраненному снимку
+			r.Post("/move", handlers.MoveTask)         // Перенос задачи перед другой задачей проекта или после нее
+
+			r.Route("/dependencies", func(r chi.Router) {
+				r.Get("/", handlers.GetDependencies)                // Блокирующие и заблокированные задачи
+				r.Put("/{blockerId}", handlers.PutDependency)       // Задача {blockerId} блокирует задачу
+				r.Delete("/{blockerId}", handlers.DeleteDependency) // Удаление зависимости
+			})
+
+			r.Route("/revisions", func(r chi.Router) {
+				r.Get("/", handlers.GetRevisions)                    // Сохраненные снимки задачи
+				r.Get("/{revision}", handlers.GetRevision)           // Снимок задачи
+				r.Get("/{revision}/diff", handlers.GetRevertPreview) // Изменения при возврате к снимку
+			})
+		})
+	})
+
+	// Проекты, объединяющие задачи.
+	r.With(handlers.RateLimit("ip"), handlers.RequireAuth, handlers.RateLimit("projects")).Route(handlers.ProjectsPath, func(r chi.Router) {
+		r.Get("/", handlers.GetProjects)  // Список проектов
+		r.Post("/", handlers.PostProject) // Создание проекта
 
-	// Группировка маршрутов API для задач.
+		r.Route("/{projectId}", func(r chi.Router) {
+			r.Get("/", handlers.GetProject)                                     // Получение проекта
+			r.Put("/", handlers.PutProject)                                     // Изменение проекта
+			r.With(handlers.RequireSession).Delete("/", handlers.DeleteProject) // Удаление проекта без задач
+			r.Post("/archive", handlers.ArchiveProject)                         // Отправка проекта с задачами в архив
+			r.Post("/unarchive", handlers.UnarchiveProject)                     // Возврат проекта из архива
+			r.Get("/tasks", handlers.GetProjectTasks)                           // Задачи проекта
+			r.Put("/order", handlers.PutProjectOrder)                           // Порядок задач проекта
+
+			r.With(handlers.RequireSession).Route("/members", func(r chi.Router) {
+				r.Get("/", handlers.GetProjectMembers)              // Владелец и участники проекта
+				r.Put("/{userId}", handlers.PutProjectMember)       // Роль участника
+				r.Delete("/{userId}", handlers.DeleteProjectMember) // Исключение участника или выход из проекта
+			})
+
+			r.With(handlers.RequireSession).Route("/invitations", func(r chi.Router) {
+				r.Get("/", handlers.GetProjectInvitations)                    // Приглашения в проект
+				r.Post("/", handlers.PostProjectInvitation)                   // Приглашение пользователя
+				r.Delete("/{invitationId}", handlers.DeleteProjectInvitation) // Отзыв приглашения
+			})
+		})
+	})
+
+	// Приглашения в проекты, адресованные пользователю.
+	r.With(handlers.RateLimit("ip"), handlers.RequireSession, handlers.RequireAuth, handlers.RateLimit("invitations")).Route(handlers.InvitationsPath, func(r chi.Router) {
+		r.Get("/", handlers.GetInvitations)                           // Приглашения пользователя
+		r.Get("/{invitationId}", handlers.GetInvitation)              // Получение приглашения
+		r.Post("/{invitationId}/accept", handlers.AcceptInvitation)   // Принятие приглашения
+		r.Post("/{invitationId}/decline", handlers.DeclineInvitation) // Отказ от приглашения
+	})
+
+	// Теги задач.
+	r.With(handlers.RateLimit("ip"), handlers.RequireAuth, handlers.RateLimit("tags")).Route(handlers.TagsPath, func(r chi.Router) {
+		r.Get("/", handlers.GetTags)             // Теги для автодополнения
+		r.Post("/", handlers.PostTag)            // Создание тега
+		r.Get("/{tagId}", handlers.GetTag)       // Получение тега
+		r.Put("/{tagId}", handlers.PutTag)       // Переименование тега
+		r.Delete("/{tagId}", handlers.DeleteTag) // Удаление тега
+	})
+
+	// Корзина удаленных задач.
+	r.With(handlers.RateLimit("ip"), handlers.RequireAuth, handlers.RateLimit("trash")).Route(handlers.TrashPath, func(r chi.Router) {
+		r.Get("/", handlers.GetTrash)             // Список задач в корзине
+		r.Delete("/", handlers.EmptyTrash)        // Очистка корзины
+		r.Delete("/{taskId}", handlers.PurgeTask) // Окончательное удаление задачи
+	})
+
+	r.With(handlers.RateLimit("ip"), handlers.RequireAuth, handlers.RateLimit("audit")).Get(handlers.AuditPath, handlers.GetAudit) // Журнал изменений всех задач
+
+	// Пользователи.
+	r.Route(handlers.UsersPath, func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.RateLimit("auth"))
+			r.Post("/", handlers.PostUser)       // Регистрация пользователя
+			r.Post("/login", handlers.LoginUser) // Вход по логину и паролю
+		})
+
+		// Учетная запись и ключи API управляются только после входа, не по ключу.
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.RateLimit("ip"), handlers.RequireSession, handlers.RequireAuth, handlers.RateLimit("users"))
+			r.Get("/me", handlers.GetProfile) // Профиль пользователя
+			r.Put("/me", handlers.PutProfile) // Изменение имени и пароля
+
+			r.Route("/me/keys", func(r chi.Router) {
+				r.Get("/", handlers.GetAPIKeys)             // Ключи API пользователя
+				r.Post("/", handlers.PostAPIKey)            // Создание ключа API
+				r.Get("/{keyId}", handlers.GetAPIKey)       // Получение ключа API
+				r.Delete("/{keyId}", handlers.DeleteAPIKey) // Отзыв ключа API
+			})
+		})
+	})
+
+	r.With(handlers.RateLimit("nextdate")).Get("/api/nextdate", handlers.GetNextDate) // Вычисление следующей даты повторяющейся задачи
+	r.With(handlers.RateLimit("auth")).Post("/api/signin", handlers.SignIn)           // Вход по паролю TODO_PASSWORD
+
+	// Устаревшие маршруты, оставленные для совместимости со старыми клиентами.
+	// Они расходуют те же запросы клиента, что и ресурс задач API v1.
 	r.Group(func(r chi.Router) {
-		r.Post("/task", handlers.PostTask)                     // Создание задачи
-		r.Put("/task", handlers.PutTask)                       // Обновление задачи
-		r.Delete("/task", handlers.DeleteTask)                 // Удаление задачи
-		r.Get("/task", handlers.GetTask)                       // Получение конкретной задачи
-		r.Post("/task/done", handlers.DonePostTask)            // Отметка задачи как выполненной
-		r.Get("/tasks", handlers.GetALLTasks)                  // API для получения списка ВСЕХ задач
-		r.Get("/status", handlers.GetTasksByStatus)            // API для получения списка списка всех задачь с определенныфм статусом
-		r.Get("/datestatus", handlers.GetTasksByDateAndStatus) // API для получения списка ВСЕХ задач с определенным статусом и за определенную дату
+		r.Use(deprecated(handlers.TasksPath), handlers.LegacyErrors, handlers.RateLimit("ip"), handlers.RequireTaskAuth, handlers.RateLimit("tasks"))
 
+		r.Post("/task", handlers.PostTask)          // Создание задачи
+		r.Put("/task", handlers.PutTask)            // Обновление задачи
+		r.Delete("/task", handlers.DeleteTask)      // Удаление задачи
+		r.Get("/task", handlers.GetTask)            // Получение конкретной задачи
+		r.Post("/task/done", handlers.DonePostTask) // Отметка задачи как выполненной
+		r.Get("/tasks", handlers.GetALLTasks)       // API для получения списка ВСЕХ задач
+		r.Get("/status", handlers.GetALLTasks)      // API для получения списка списка всех задачь с определенныфм статусом
+		r.Get("/datestatus", handlers.GetALLTasks)  // API для получения списка ВСЕХ задач с определенным статусом и за определенную дату
 	})
 
+	return r
+}
+
+// StartServer запускает веб-сервер
+func StartServer() {
+	r := NewRouter()
+
 	// Старт веб-сервера на указанном порту.
 	port := settings.Setting("TODO_PORT")
 	serverAddr := ":" + port
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"context"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// permission действие, которое роль пользователя в проекте разрешает или запрещает
+type permission int
+
+const (
+	// readTasks просмотр задач проекта
+	readTasks permission = iota
+	// writeTasks создание, изменение и удаление задач проекта
+	writeTasks
+	// manageProject изменение проекта и управление его участниками
+	manageProject
+	// deleteProject удаление проекта
+	deleteProject
+)
+
+// roleLevels наибольшее действие, которое разрешает роль; роль разрешает и все предыдущие действия
+var roleLevels = map[string]permission{
+	model.RoleViewer: readTasks,
+	model.RoleEditor: writeTasks,
+	model.RoleAdmin:  manageProject,
+	model.RoleOwner:  deleteProject,
+}
+
+// allows проверяет, разрешает ли роль role действие perm. Пустая роль не разрешает ничего.
+func allows(role string, perm permission) bool {
+	level, ok := roleLevels[role]
+	return ok && level >= perm
+}
+
+// authorizeTask проверяет, что пользователь может выполнить действие perm с задачей id, в том числе из корзины.
+// Права на задачу определяет роль пользователя в ее проекте, а во Входящих, где ролей нет,
+// задачей распоряжается ее владелец. Недоступная пользователю задача для него не существует: ErrNotFoundTask,
+// а если доступная задача не разрешает действие - ErrForbidden.
+func (service TaskService) authorizeTask(ctx context.Context, id int64, perm permission) error {
+	owner, role, err := service.taskData.TaskAccess(ctx, id)
+	if err != nil {
+		return notFound(err)
+	}
+	if len(role) == 0 && owner == database.OwnerFrom(ctx) {
+		role = model.RoleOwner
+	}
+	if !allows(role, perm) {
+		return taskerror.ErrForbidden
+	}
+	return nil
+}
+
+// authorizeProject проверяет, что роль пользователя в доступном ему проекте разрешает действие perm.
+// Во Входящих каждый пользователь работает со своими задачами, но изменить сами Входящие нельзя.
+func authorizeProject(project model.Project, perm permission) error {
+	if project.Id == model.InboxProjectId {
+		if perm > writeTasks {
+			return taskerror.ErrInboxProject
+		}
+		return nil
+	}
+	if !allows(project.Role, perm) {
+		return taskerror.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/auth"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// parseAPIKeyID разбирает идентификатор ключа API из запроса
+func parseAPIKeyID(id string) (int64, error) {
+	convId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || convId <= 0 {
+		return 0, taskerror.ErrInvalidKeyID
+	}
+	return convId, nil
+}
+
+// checkScopes проверяет, что у ключа есть разрешения и все они известны; повторы разрешений удаляются
+func checkScopes(errs *taskerror.FieldErrors, scopes []string) []string {
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope != model.ScopeTasksRead && scope != model.ScopeTasksWrite {
+			errs.Add("scopes", taskerror.ErrInvalidScope)
+			return nil
+		}
+		if !(model.APIKey{Scopes: unique}).Allows(scope) {
+			unique = append(unique, scope)
+		}
+	}
+	if len(unique) == 0 {
+		errs.Add("scopes", taskerror.ErrInvalidScope)
+	}
+	return unique
+}
+
+// CreateAPIKey создает ключ API пользователя, от имени которого выполняется запрос. Ключ Key
+// возвращается только в ответе на этот запрос: сохраняется лишь его хеш.
+func (service TaskService) CreateAPIKey(ctx context.Context, input model.APIKeyInput) (*model.APIKey, error) {
+	var errs taskerror.FieldErrors
+	key := model.APIKey{Name: strings.TrimSpace(input.Name), ExpiresAt: input.ExpiresAt}
+	if len(key.Name) == 0 {
+		errs.Add("name", taskerror.ErrRequireKeyName)
+	}
+	checkLength(&errs, "name", key.Name, settings.APIKeyNameMaxLength)
+	key.Scopes = checkScopes(&errs, input.Scopes)
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		errs.Add("expires_at", taskerror.ErrInvalidExpiry)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	secret, prefix, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key.Prefix, key.KeyHash = prefix, auth.HashAPIKey(secret)
+	id, err := service.taskData.InsertAPIKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	created, err := service.taskData.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	created.Key = secret
+	return &created, nil
+}
+
+// ListAPIKeys возвращает ключи API пользователя без самих ключей
+func (service TaskService) ListAPIKeys(ctx context.Context) (*model.APIKeyList, error) {
+	keys, err := service.taskData.FindAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.APIKeyList{Keys: keys}, nil
+}
+
+// GetAPIKey возвращает ключ API пользователя без самого ключа
+func (service TaskService) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	convId, err := parseAPIKeyID(id)
+	if err != nil {
+		return nil, err
+	}
+	key, err := service.taskData.GetAPIKey(ctx, convId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, taskerror.ErrNotFoundAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey отзывает ключ API пользователя: запросы с ним сразу перестают приниматься
+func (service TaskService) RevokeAPIKey(ctx context.Context, id string) error {
+	convId, err := parseAPIKeyID(id)
+	if err != nil {
+		return err
+	}
+	deleted, err := service.taskData.DeleteAPIKey(ctx, convId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return taskerror.ErrNotFoundAPIKey
+	}
+	return nil
+}
+
+// AuthenticateAPIKey проверяет ключ API из запроса и возвращает ключ и его владельца,
+// отмечая время использования ключа. Неизвестный ключ - ErrUnauthorized, просроченный - ErrAPIKeyExpired.
+func (service TaskService) AuthenticateAPIKey(ctx context.Context, secret string) (*model.APIKey, *model.User, error) {
+	key, err := service.taskData.GetAPIKeyByHash(ctx, auth.HashAPIKey(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, taskerror.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt) {
+		return nil, nil, taskerror.ErrAPIKeyExpired
+	}
+	user, err := service.GetUser(ctx, key.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := service.taskData.TouchAPIKey(ctx, key.Id); err != nil {
+		return nil, nil, err
+	}
+	return &key, user, nil
+}
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// eventActions допустимые действия журнала изменений
+var eventActions = map[string]bool{
+	model.ActionCreate:  true,
+	model.ActionUpdate:  true,
+	model.ActionDone:    true,
+	model.ActionReopen:  true,
+	model.ActionDelete:  true,
+	model.ActionRestore: true,
+	model.ActionPurge:   true,
+	model.ActionRevert:  true,
+}
+
+// TaskHistory возвращает страницу истории изменений задачи, в том числе удаленной
+func (service TaskService) TaskHistory(ctx context.Context, id string, filter model.EventFilter) (*model.TaskEventList, error) {
+	convId, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	filter.TaskId = convId
+	list, err := service.ListAudit(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Events) == 0 && len(filter.Cursor) == 0 {
+		// Пустая история без фильтров означает, что задачи никогда не было
+		events, _, err := service.taskData.FindEvents(ctx, model.EventFilter{TaskId: convId, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			return nil, taskerror.ErrNotFoundTask
+		}
+	}
+	return list, nil
+}
+
+// ListAudit возвращает страницу журнала изменений всех задач
+func (service TaskService) ListAudit(ctx context.Context, filter model.EventFilter) (*model.TaskEventList, error) {
+	if filter.Limit == 0 {
+		filter.Limit = settings.TasksListRowsLimit
+	}
+	if err := validateEventFilter(filter); err != nil {
+		return nil, err
+	}
+	events, next, err := service.taskData.FindEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TaskEventList{Events: events, NextCursor: next}, nil
+}
+
+// validateEventFilter проверяет параметры выборки журнала изменений
+func validateEventFilter(filter model.EventFilter) error {
+	var errs taskerror.FieldErrors
+	for _, action := range filter.Actions {
+		if !eventActions[action] {
+			errs.Add("action", taskerror.ErrInvalidAction)
+			break
+		}
+	}
+	if filter.Limit < 0 || filter.Limit > settings.TasksListMaxLimit {
+		errs.Add("limit", fmt.Errorf("%w: expected 1 - %d", taskerror.ErrInvalidLimit, settings.TasksListMaxLimit))
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		errs.Add("from", taskerror.ErrInvalidTimeRange)
+	}
+	return errs.Err()
+}
@@ -0,0 +1,168 @@
+package task
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// isBlocked проверяет, заблокирована ли невыполненная и неотмененная задача:
+// она в статусе blocked или ее блокирует невыполненная задача
+func isBlocked(task model.Task) bool {
+	if task.Status == model.StatusDone || task.Status == model.StatusCancelled {
+		return false
+	}
+	return task.Status == model.StatusBlocked || len(task.BlockedBy) > 0
+}
+
+// dependencyTasks разбирает ID задачи и блокирующей ее задачи и проверяет, что обе задачи есть
+// и пользователь может изменять зависимости задачи
+func (service TaskService) dependencyTasks(ctx context.Context, id, blockerID string) (int64, int64, error) {
+	task, err := service.GetTask(ctx, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := service.authorizeTask(ctx, task.Id, writeTasks); err != nil {
+		return 0, 0, err
+	}
+	blocker, err := service.GetTask(ctx, blockerID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return task.Id, blocker.Id, nil
+}
+
+// AddDependency делает задачу blockerID блокирующей для задачи id.
+// Повторное добавление той же зависимости ничего не меняет, а зависимость, замыкающая цикл, отклоняется.
+func (service TaskService) AddDependency(ctx context.Context, id, blockerID string) error {
+	taskID, blocker, err := service.dependencyTasks(ctx, id, blockerID)
+	if err != nil {
+		return err
+	}
+	_, err = service.taskData.AddDependency(ctx, blocker, taskID)
+	return err
+}
+
+// RemoveDependency удаляет зависимость задачи id от задачи blockerID
+func (service TaskService) RemoveDependency(ctx context.Context, id, blockerID string) error {
+	taskID, blocker, err := service.dependencyTasks(ctx, id, blockerID)
+	if err != nil {
+		return err
+	}
+	removed, err := service.taskData.RemoveDependency(ctx, blocker, taskID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return taskerror.ErrNotFoundDependency
+	}
+	return nil
+}
+
+// GetDependencies возвращает задачи, которые блокируют задачу id, и задачи, которые она блокирует
+func (service TaskService) GetDependencies(ctx context.Context, id string) (*model.TaskDependencies, error) {
+	task, err := service.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := service.taskData.FindDependencies(ctx, task.Id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, tasks := range [][]model.Task{deps.BlockedBy, deps.Blocks} {
+		for i := range tasks {
+			tasks[i].Overdue, tasks[i].Blocked = isOverdue(tasks[i], now), isBlocked(tasks[i])
+		}
+	}
+	return &deps, nil
+}
+
+// ListActionable возвращает страницу задач, которые можно начать прямо сейчас,
+// по умолчанию упорядоченных по приоритету и сроку
+func (service TaskService) ListActionable(ctx context.Context, filter model.TaskFilter) (*model.TaskList, error) {
+	filter.Actionable = true
+	if len(filter.Sort) == 0 && !database.IsSearchQuery(filter.Search) {
+		filter.Sort = "priority"
+	}
+	return service.ListTasks(ctx, filter)
+}
+
+// PlanTasks возвращает невыполненные и неотмененные задачи в порядке выполнения:
+// каждая задача идет после задач, которые ее блокируют. Из задач, которые можно выполнять
+// одновременно, раньше идет задача с более высоким приоритетом и более ранним сроком.
+// В план попадает не больше settings.TaskPlanLimit задач с наивысшим приоритетом.
+func (service TaskService) PlanTasks(ctx context.Context) (*model.TaskList, error) {
+	filter := model.TaskFilter{
+		Statuses: []string{model.StatusTodo, model.StatusInProgress, model.StatusBlocked},
+		Sort:     "priority",
+		Limit:    settings.TaskPlanLimit,
+	}
+	open, _, err := service.taskData.FindTasks(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range open {
+		open[i].Overdue, open[i].Blocked = isOverdue(open[i], now), isBlocked(open[i])
+	}
+	return sliceToTasks(topologicalOrder(open)), nil
+}
+
+// topologicalOrder упорядочивает задачи так, чтобы блокирующие задачи шли раньше заблокированных
+// (алгоритм Кана). Из готовых к выполнению задач первой берется задача, которая раньше в tasks.
+// Блокирующие задачи, которых нет в tasks, не учитываются.
+func topologicalOrder(tasks []model.Task) []model.Task {
+	index := make(map[int64]int, len(tasks))
+	for i, task := range tasks {
+		index[task.Id] = i
+	}
+	waiting := make([]int, len(tasks))
+	blocks := make([][]int, len(tasks))
+	for i, task := range tasks {
+		for _, blockerID := range task.BlockedBy {
+			if blocker, ok := index[blockerID]; ok {
+				waiting[i]++
+				blocks[blocker] = append(blocks[blocker], i)
+			}
+		}
+	}
+
+	ready := &indexHeap{}
+	for i := range tasks {
+		if waiting[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+	ordered := make([]model.Task, 0, len(tasks))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		ordered = append(ordered, tasks[i])
+		for _, blocked := range blocks[i] {
+			if waiting[blocked]--; waiting[blocked] == 0 {
+				heap.Push(ready, blocked)
+			}
+		}
+	}
+	return ordered
+}
+
+// indexHeap очередь индексов задач, из которой первым извлекается наименьший индекс
+type indexHeap []int
+
+func (h indexHeap) Len() int           { return len(h) }
+func (h indexHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
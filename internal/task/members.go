@@ -0,0 +1,278 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// parseUserID разбирает идентификатор пользователя из запроса
+func parseUserID(id string) (int64, error) {
+	convId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || convId <= 0 {
+		return 0, taskerror.ErrInvalidUserID
+	}
+	return convId, nil
+}
+
+// parseInvitationID разбирает идентификатор приглашения из запроса
+func parseInvitationID(id string) (int64, error) {
+	convId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || convId <= 0 {
+		return 0, taskerror.ErrInvalidInviteID
+	}
+	return convId, nil
+}
+
+// invitationNotFound заменяет отсутствие строки в базе данных на ошибку ErrNotFoundInvitation
+func invitationNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return taskerror.ErrNotFoundInvitation
+	}
+	return err
+}
+
+// checkRole проверяет роль, которую можно назначить участнику проекта: владелец у проекта один
+func checkRole(errs *taskerror.FieldErrors, role string) {
+	if role != model.RoleViewer && role != model.RoleEditor && role != model.RoleAdmin {
+		errs.Add("role", taskerror.ErrInvalidRole)
+	}
+}
+
+// ListMembers возвращает владельца и участников проекта. Список видят все участники.
+func (service TaskService) ListMembers(ctx context.Context, projectID string) (*model.ProjectMemberList, error) {
+	project, err := service.authorizedProject(ctx, projectID, readTasks)
+	if err != nil {
+		return nil, err
+	}
+	members, err := service.taskData.FindMembers(ctx, project.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ProjectMemberList{Members: members}, nil
+}
+
+// UpdateMember назначает участнику проекта userID роль member.Role и возвращает участника.
+// Роль владельца проекта изменить нельзя.
+func (service TaskService) UpdateMember(ctx context.Context, projectID, userID string, member model.ProjectMember) (*model.ProjectMember, error) {
+	project, err := service.authorizedProject(ctx, projectID, manageProject)
+	if err != nil {
+		return nil, err
+	}
+	if member.UserId, err = parseUserID(userID); err != nil {
+		return nil, err
+	}
+	var errs taskerror.FieldErrors
+	checkRole(&errs, member.Role)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	if member.UserId == project.OwnerId {
+		return nil, taskerror.ErrProjectOwner
+	}
+	updated, err := service.taskData.UpdateMember(ctx, project.Id, member)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, taskerror.ErrNotFoundMember
+	}
+	members, err := service.taskData.FindMembers(ctx, project.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, current := range members {
+		if current.UserId == member.UserId {
+			return &current, nil
+		}
+	}
+	return nil, taskerror.ErrNotFoundMember
+}
+
+// RemoveMember исключает участника userID из проекта. Участник может покинуть проект сам,
+// исключать других может администратор. Владельца исключить нельзя.
+// Задачи исключенного участника остаются в проекте, а сам он теряет доступ и к ним.
+func (service TaskService) RemoveMember(ctx context.Context, projectID, userID string) error {
+	project, err := service.GetProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	convId, err := parseUserID(userID)
+	if err != nil {
+		return err
+	}
+	perm := manageProject
+	if convId == database.OwnerFrom(ctx) {
+		perm = readTasks
+	}
+	if err := authorizeProject(*project, perm); err != nil {
+		return err
+	}
+	if convId == project.OwnerId {
+		return taskerror.ErrProjectOwner
+	}
+	deleted, err := service.taskData.DeleteMember(ctx, project.Id, convId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return taskerror.ErrNotFoundMember
+	}
+	return nil
+}
+
+// InviteMember приглашает пользователя invitation.Login в проект с ролью invitation.Role
+// и возвращает ID приглашения. Участником пользователь станет, когда примет приглашение.
+func (service TaskService) InviteMember(ctx context.Context, projectID string, invitation model.Invitation) (int64, error) {
+	project, err := service.authorizedProject(ctx, projectID, manageProject)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs taskerror.FieldErrors
+	user, err := service.taskData.GetUserByLogin(ctx, normalizeLogin(invitation.Login))
+	if errors.Is(err, sql.ErrNoRows) {
+		errs.Add("login", taskerror.ErrUnknownUser)
+	} else if err != nil {
+		return 0, err
+	}
+	checkRole(&errs, invitation.Role)
+	if err := errs.Err(); err != nil {
+		return 0, err
+	}
+	if user.Id == project.OwnerId {
+		return 0, taskerror.ErrProjectOwner
+	}
+
+	members, err := service.taskData.FindMembers(ctx, project.Id)
+	if err != nil {
+		return 0, err
+	}
+	for _, member := range members {
+		if member.UserId == user.Id {
+			return 0, taskerror.ErrAlreadyMember
+		}
+	}
+	invitations, err := service.taskData.FindInvitations(ctx, project.Id)
+	if err != nil {
+		return 0, err
+	}
+	for _, pending := range invitations {
+		if pending.UserId == user.Id {
+			return 0, taskerror.ErrAlreadyInvited
+		}
+	}
+
+	invitation.ProjectId, invitation.UserId = project.Id, user.Id
+	return service.taskData.InsertInvitation(ctx, invitation)
+}
+
+// ListProjectInvitations возвращает приглашения в проект, которые еще не приняты и не отклонены
+func (service TaskService) ListProjectInvitations(ctx context.Context, projectID string) (*model.InvitationList, error) {
+	project, err := service.authorizedProject(ctx, projectID, manageProject)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := service.taskData.FindInvitations(ctx, project.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.InvitationList{Invitations: invitations}, nil
+}
+
+// RevokeInvitation отзывает приглашение invitationID в проект
+func (service TaskService) RevokeInvitation(ctx context.Context, projectID, invitationID string) error {
+	project, err := service.authorizedProject(ctx, projectID, manageProject)
+	if err != nil {
+		return err
+	}
+	convId, err := parseInvitationID(invitationID)
+	if err != nil {
+		return err
+	}
+	invitation, err := service.taskData.GetInvitation(ctx, convId)
+	if err != nil {
+		return invitationNotFound(err)
+	}
+	if invitation.ProjectId != project.Id {
+		return taskerror.ErrNotFoundInvitation
+	}
+	return service.deleteInvitation(ctx, convId)
+}
+
+// ListInvitations возвращает приглашения, адресованные пользователю
+func (service TaskService) ListInvitations(ctx context.Context) (*model.InvitationList, error) {
+	invitations, err := service.taskData.FindInvitations(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &model.InvitationList{Invitations: invitations}, nil
+}
+
+// GetInvitation возвращает приглашение. Его видят приглашенный пользователь и администраторы проекта.
+func (service TaskService) GetInvitation(ctx context.Context, id string) (*model.Invitation, error) {
+	convId, err := parseInvitationID(id)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := service.taskData.GetInvitation(ctx, convId)
+	if err != nil {
+		return nil, invitationNotFound(err)
+	}
+	if invitation.UserId != database.OwnerFrom(ctx) {
+		project, err := service.taskData.GetProject(ctx, invitation.ProjectId)
+		if err != nil || authorizeProject(project, manageProject) != nil {
+			return nil, taskerror.ErrNotFoundInvitation
+		}
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation принимает адресованное пользователю приглашение, и он становится участником проекта
+func (service TaskService) AcceptInvitation(ctx context.Context, id string) error {
+	convId, err := parseInvitationID(id)
+	if err != nil {
+		return err
+	}
+	accepted, err := service.taskData.AcceptInvitation(ctx, convId)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return taskerror.ErrNotFoundInvitation
+	}
+	return nil
+}
+
+// DeclineInvitation отклоняет адресованное пользователю приглашение
+func (service TaskService) DeclineInvitation(ctx context.Context, id string) error {
+	convId, err := parseInvitationID(id)
+	if err != nil {
+		return err
+	}
+	invitation, err := service.taskData.GetInvitation(ctx, convId)
+	if err != nil {
+		return invitationNotFound(err)
+	}
+	if invitation.UserId != database.OwnerFrom(ctx) {
+		return taskerror.ErrNotFoundInvitation
+	}
+	return service.deleteInvitation(ctx, convId)
+}
+
+// deleteInvitation удаляет приглашение, которое пользователь может видеть
+func (service TaskService) deleteInvitation(ctx context.Context, id int64) error {
+	deleted, err := service.taskData.DeleteInvitation(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return taskerror.ErrNotFoundInvitation
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+const (
+	// maxRepeatDays наибольший интервал правила "d N"
+	maxRepeatDays = 400
+	// searchHorizonDays предел поиска даты для правил "w" и "m". Десяти лет достаточно,
+	// чтобы встретить любое существующее сочетание числа и месяца, включая 29 февраля.
+	searchHorizonDays = 3660
+)
+
+// repeatRule разобранное правило повторения задачи
+type repeatRule struct {
+	kind byte
+	// days интервал в днях для правила "d"
+	days int
+	// weekdays дни недели для правила "w", 1 - понедельник, 7 - воскресенье
+	weekdays map[time.Weekday]bool
+	// monthDays числа месяца для правила "m", -1 - последний день, -2 - предпоследний
+	monthDays map[int]bool
+	// months месяцы для правила "m", пусто - любой месяц
+	months map[time.Month]bool
+}
+
+// NextDate возвращает следующую дату выполнения задачи в формате settings.DateFormat.
+// Результат строго больше и даты задачи date, и текущей даты now.
+//
+// Поддерживаемые правила repeat:
+//   - "d N" - каждые N дней (1 <= N <= 400), отсчет от date;
+//   - "y" - ежегодно в тот же день, 29 февраля переносится на 1 марта;
+//   - "w 1,4,5" - по дням недели, 1 - понедельник, 7 - воскресенье;
+//   - "m 1,15,-1 [1,6,12]" - по числам месяца, -1 и -2 - последний и предпоследний день;
+//     необязательный второй список ограничивает месяцы.
+func NextDate(now time.Time, date string, repeat string) (string, error) {
+	start, err := time.Parse(settings.DateFormat, date)
+	if err != nil {
+		return "", taskerror.ErrInvalidDate
+	}
+	rule, err := parseRepeat(repeat)
+	if err != nil {
+		return "", err
+	}
+	now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var next time.Time
+	switch rule.kind {
+	case 'd', 'y':
+		next = start
+		for {
+			if rule.kind == 'd' {
+				next = next.AddDate(0, 0, rule.days)
+			} else {
+				next = next.AddDate(1, 0, 0)
+			}
+			if next.After(now) {
+				break
+			}
+		}
+	default:
+		if now.After(start) {
+			start = now
+		}
+		next, err = rule.firstMatchAfter(start)
+		if err != nil {
+			return "", err
+		}
+	}
+	return next.Format(settings.DateFormat), nil
+}
+
+// ValidateRepeat проверяет синтаксис правила повторения
+func ValidateRepeat(repeat string) error {
+	_, err := parseRepeat(repeat)
+	return err
+}
+
+// parseRepeat разбирает правило повторения
+func parseRepeat(repeat string) (repeatRule, error) {
+	fields := strings.Fields(repeat)
+	if len(fields) == 0 || len(fields[0]) != 1 {
+		return repeatRule{}, invalidRepeat(repeat)
+	}
+
+	rule := repeatRule{kind: fields[0][0]}
+	args := fields[1:]
+	switch rule.kind {
+	case 'd':
+		if len(args) != 1 {
+			return rule, invalidRepeat(repeat)
+		}
+		days, err := strconv.Atoi(args[0])
+		if err != nil || days < 1 || days > maxRepeatDays {
+			return rule, invalidRepeat(repeat)
+		}
+		rule.days = days
+	case 'y':
+		if len(args) != 0 {
+			return rule, invalidRepeat(repeat)
+		}
+	case 'w':
+		if len(args) != 1 {
+			return rule, invalidRepeat(repeat)
+		}
+		days, err := parseNumbers(args[0], func(n int) bool { return n >= 1 && n <= 7 })
+		if err != nil {
+			return rule, invalidRepeat(repeat)
+		}
+		rule.weekdays = map[time.Weekday]bool{}
+		for _, d := range days {
+			rule.weekdays[time.Weekday(d%7)] = true
+		}
+	case 'm':
+		if len(args) < 1 || len(args) > 2 {
+			return rule, invalidRepeat(repeat)
+		}
+		days, err := parseNumbers(args[0], func(n int) bool { return (n >= 1 && n <= 31) || n == -1 || n == -2 })
+		if err != nil {
+			return rule, invalidRepeat(repeat)
+		}
+		rule.monthDays = map[int]bool{}
+		for _, d := range days {
+			rule.monthDays[d] = true
+		}
+		if len(args) == 2 {
+			months, err := parseNumbers(args[1], func(n int) bool { return n >= 1 && n <= 12 })
+			if err != nil {
+				return rule, invalidRepeat(repeat)
+			}
+			rule.months = map[time.Month]bool{}
+			for _, m := range months {
+				rule.months[time.Month(m)] = true
+			}
+		}
+	default:
+		return rule, invalidRepeat(repeat)
+	}
+	return rule, nil
+}
+
+// parseNumbers разбирает список чисел через запятую, проверяя каждое функцией valid
+func parseNumbers(list string, valid func(n int) bool) ([]int, error) {
+	var numbers []int
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		if !valid(n) {
+			return nil, fmt.Errorf("value %d out of range", n)
+		}
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+// firstMatchAfter возвращает первый день после start, подходящий под правило "w" или "m"
+func (rule repeatRule) firstMatchAfter(start time.Time) (time.Time, error) {
+	day := start
+	for i := 0; i < searchHorizonDays; i++ {
+		day = day.AddDate(0, 0, 1)
+		if rule.matches(day) {
+			return day, nil
+		}
+	}
+	return time.Time{}, taskerror.ErrInvalidRepeat
+}
+
+// matches проверяет, подходит ли день под правило "w" или "m"
+func (rule repeatRule) matches(day time.Time) bool {
+	if rule.kind == 'w' {
+		return rule.weekdays[day.Weekday()]
+	}
+	if len(rule.months) > 0 && !rule.months[day.Month()] {
+		return false
+	}
+	// Число месяца, отсчитанное от конца: -1 для последнего дня
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	fromEnd := day.Day() - daysInMonth - 1
+	return rule.monthDays[day.Day()] || rule.monthDays[fromEnd]
+}
+
+// invalidRepeat возвращает ошибку неверного правила повторения
+func invalidRepeat(repeat string) error {
+	return fmt.Errorf("%w: %q", taskerror.ErrInvalidRepeat, repeat)
+}
@@ -0,0 +1,14 @@
+package task
+
+// Precondition проверяет текущую версию задачи перед ее изменением и возвращает
+// taskerror.ErrPreconditionFailed, если изменять задачу этой версии нельзя.
+// nil означает изменение без условий.
+type Precondition func(version int64) error
+
+// check проверяет условие для версии version
+func (cond Precondition) check(version int64) error {
+	if cond == nil {
+		return nil
+	}
+	return cond(version)
+}
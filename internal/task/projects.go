@@ -0,0 +1,241 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// parseProjectID разбирает идентификатор проекта из запроса
+func parseProjectID(id string) (int64, error) {
+	convId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || convId <= 0 {
+		return 0, taskerror.ErrInvalidProjectID
+	}
+	return convId, nil
+}
+
+// projectNotFound заменяет отсутствие строки в базе данных на ошибку ErrNotFoundProject
+func projectNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return taskerror.ErrNotFoundProject
+	}
+	return err
+}
+
+// ListProjects возвращает действующие или, если archived, архивные проекты
+func (service TaskService) ListProjects(ctx context.Context, archived bool) (*model.ProjectList, error) {
+	projects, err := service.taskData.FindProjects(ctx, archived)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ProjectList{Projects: projects}, nil
+}
+
+// GetProject возвращает проект по идентификатору
+func (service TaskService) GetProject(ctx context.Context, id string) (*model.Project, error) {
+	convId, err := parseProjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	project, err := service.taskData.GetProject(ctx, convId)
+	if err != nil {
+		return nil, projectNotFound(err)
+	}
+	return &project, nil
+}
+
+// CreateProject создает проект и возвращает его ID
+func (service TaskService) CreateProject(ctx context.Context, project model.Project) (int64, error) {
+	if err := service.validateProject(ctx, &project, 0); err != nil {
+		return 0, err
+	}
+	return service.taskData.InsertProject(ctx, project)
+}
+
+// UpdateProject изменяет имя и описание проекта. Входящие общие для всех пользователей, их изменить нельзя.
+// Имя проекта должно быть уникальным среди проектов его владельца.
+func (service TaskService) UpdateProject(ctx context.Context, id string, project model.Project) (*model.Project, error) {
+	current, err := service.authorizedProject(ctx, id, manageProject)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.validateProject(database.WithOwner(ctx, current.OwnerId), &project, current.Id); err != nil {
+		return nil, err
+	}
+	project.Id = current.Id
+	updated, err := service.taskData.UpdateProject(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, taskerror.ErrNotFoundProject
+	}
+	return service.GetProject(ctx, id)
+}
+
+// ArchiveProject отправляет проект вместе с задачами в архив или, если archived false, возвращает из архива.
+// Задачи архивного проекта остаются доступны по ID и в списке задач проекта.
+func (service TaskService) ArchiveProject(ctx context.Context, id string, archived bool) error {
+	project, err := service.authorizedProject(ctx, id, manageProject)
+	if err != nil {
+		return err
+	}
+	changed, err := service.taskData.ArchiveProject(ctx, project.Id, archived)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return taskerror.ErrNotFoundProject
+	}
+	return nil
+}
+
+// DeleteProject удаляет проект. Удалить можно только проект без задач, в том числе в корзине,
+// и только его владельцу.
+func (service TaskService) DeleteProject(ctx context.Context, id string) error {
+	project, err := service.authorizedProject(ctx, id, deleteProject)
+	if err != nil {
+		return err
+	}
+	deleted, err := service.taskData.DeleteProject(ctx, project.Id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return taskerror.ErrProjectNotEmpty
+	}
+	return nil
+}
+
+// ListProjectTasks возвращает страницу задач проекта, по умолчанию в порядке проекта
+func (service TaskService) ListProjectTasks(ctx context.Context, id string, filter model.TaskFilter) (*model.TaskList, error) {
+	project, err := service.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	filter.ProjectId = project.Id
+	if len(filter.Sort) == 0 && !database.IsSearchQuery(filter.Search) {
+		filter.Sort = "position"
+	}
+	return service.ListTasks(ctx, filter)
+}
+
+// ReorderProject задает порядок задач проекта: задачи taskIDs идут первыми в указанном порядке,
+// остальные задачи проекта следуют за ними в прежнем порядке
+func (service TaskService) ReorderProject(ctx context.Context, id string, taskIDs []int64) error {
+	project, err := service.authorizedProject(ctx, id, writeTasks)
+	if err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(taskIDs))
+	for _, taskID := range taskIDs {
+		if seen[taskID] {
+			return taskerror.ErrInvalidTaskOrder
+		}
+		seen[taskID] = true
+	}
+	reordered, err := service.taskData.ReorderTasks(ctx, project.Id, taskIDs)
+	if err != nil {
+		return err
+	}
+	if !reordered {
+		return taskerror.ErrInvalidTaskOrder
+	}
+	return nil
+}
+
+// MoveTask переносит задачу id вплотную перед задачей move.Before или после задачи move.After того же проекта
+func (service TaskService) MoveTask(ctx context.Context, id string, move model.TaskMove) error {
+	task, err := service.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := service.authorizeTask(ctx, task.Id, writeTasks); err != nil {
+		return err
+	}
+	if (move.Before > 0) == (move.After > 0) || move.Before < 0 || move.After < 0 {
+		return taskerror.ErrInvalidMove
+	}
+	target, field := move.Before, "before"
+	if move.After > 0 {
+		target, field = move.After, "after"
+	}
+	moved, err := service.taskData.MoveTask(ctx, task.Id, target, move.After > 0)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		var errs taskerror.FieldErrors
+		errs.Add(field, taskerror.ErrInvalidMoveTarget)
+		return errs.Err()
+	}
+	return nil
+}
+
+// authorizedProject возвращает проект id, если роль пользователя в нем разрешает действие perm.
+// Недоступный пользователю проект для него не существует.
+func (service TaskService) authorizedProject(ctx context.Context, id string, perm permission) (*model.Project, error) {
+	project, err := service.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeProject(*project, perm); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// validateProject проверяет проект и что его имя не занято другим проектом, кроме проекта self
+func (service TaskService) validateProject(ctx context.Context, project *model.Project, self int64) error {
+	var errs taskerror.FieldErrors
+	project.Name = strings.TrimSpace(project.Name)
+	if len(project.Name) == 0 {
+		errs.Add("name", taskerror.ErrRequireProjectName)
+	}
+	checkLength(&errs, "name", project.Name, settings.ProjectNameMaxLength)
+	checkLength(&errs, "description", project.Description, settings.TaskDescriptionMaxLength)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	existing, err := service.taskData.GetProjectByName(ctx, project.Name)
+	if err == nil && existing.Id != self {
+		return taskerror.ErrProjectExists
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// checkProject проверяет проект projectID, в который попадает задача из проекта current.
+// 0 означает Входящие при создании и прежний проект при изменении.
+// Создать задачу в архивном проекте или перенести ее туда нельзя, как и в проект, роль в котором
+// не разрешает изменять задачи.
+func (service TaskService) checkProject(ctx context.Context, projectID, current int64) error {
+	if projectID == 0 || projectID == current {
+		return nil
+	}
+	project, err := service.taskData.GetProject(ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return taskerror.ErrInvalidProject
+	}
+	if err != nil {
+		return err
+	}
+	if err := authorizeProject(project, writeTasks); err != nil {
+		return err
+	}
+	if project.ArchivedAt != nil {
+		return taskerror.ErrProjectArchived
+	}
+	return nil
+}
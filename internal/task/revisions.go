@@ -0,0 +1,146 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// parseRevision разбирает номер снимка задачи из запроса
+func parseRevision(revision string) (int64, error) {
+	n, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, taskerror.ErrInvalidRevision
+	}
+	return n, nil
+}
+
+// ListRevisions возвращает сохраненные снимки задачи от новых к старым
+func (service TaskService) ListRevisions(ctx context.Context, id string) ([]model.TaskRevision, error) {
+	convId, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := service.taskData.FindRevisions(ctx, convId)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, taskerror.ErrNotFoundTask
+	}
+	return revisions, nil
+}
+
+// GetRevision возвращает снимок задачи с номером revision
+func (service TaskService) GetRevision(ctx context.Context, id, revision string) (*model.TaskRevision, error) {
+	convId, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseRevision(revision)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := service.getRevision(ctx, convId, n)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// getRevision читает снимок задачи; если снимка нет, объясняет, нет ли задачи или только снимка
+func (service TaskService) getRevision(ctx context.Context, id, revision int64) (model.TaskRevision, error) {
+	snapshot, err := service.taskData.GetRevision(ctx, id, revision)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return snapshot, err
+	}
+	if _, err := service.taskData.GetTask(ctx, id); err != nil {
+		return snapshot, notFound(err)
+	}
+	return snapshot, taskerror.ErrNoRevision
+}
+
+// PreviewRevert показывает, какие поля изменит возврат задачи к снимку revision
+func (service TaskService) PreviewRevert(ctx context.Context, id, revision string) (*model.RevertPreview, error) {
+	current, reverted, err := service.revertTarget(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevertPreview{
+		Revision: reverted.Version,
+		Version:  current.Version,
+		Changes:  database.DiffTask(&current, &reverted),
+	}, nil
+}
+
+// RevertTask возвращает поля задачи к снимку revision, если текущая версия задачи
+// удовлетворяет условию cond. Возврат сохраняется как новый снимок, поэтому его тоже можно отменить.
+func (service TaskService) RevertTask(ctx context.Context, id, revision string, cond Precondition) (*model.Task, error) {
+	current, reverted, err := service.revertTarget(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorizeTask(ctx, current.Id, writeTasks); err != nil {
+		return nil, err
+	}
+	if err := cond.check(current.Version); err != nil {
+		return nil, err
+	}
+	reverted.Version = current.Version
+	if err := service.saveTask(ctx, reverted, model.ActionRevert, cond); err != nil {
+		return nil, err
+	}
+	if reverted.Status == model.StatusDone && current.Status != model.StatusDone {
+		if err := service.completeSubtasks(ctx, current.Id, reverted.CompletedAt); err != nil {
+			return nil, err
+		}
+	}
+	return service.GetTask(ctx, id)
+}
+
+// revertTarget возвращает текущее состояние задачи и состояние, к которому ее вернет снимок revision.
+// Версия возвращаемого состояния равна номеру снимка. Статус снимка подчиняется тем же правилам
+// переходов, что и при обновлении задачи: выполненную или отмененную задачу возврат не откроет.
+func (service TaskService) revertTarget(ctx context.Context, id, revision string) (current, reverted model.Task, err error) {
+	convId, err := parseID(id)
+	if err != nil {
+		return current, reverted, err
+	}
+	n, err := parseRevision(revision)
+	if err != nil {
+		return current, reverted, err
+	}
+	if current, err = service.taskData.GetTask(ctx, convId); err != nil {
+		return current, reverted, notFound(err)
+	}
+	// Возврат к текущей или будущей версии не имеет смысла
+	if n >= current.Version {
+		return current, reverted, taskerror.ErrInvalidRevision
+	}
+	snapshot, err := service.getRevision(ctx, convId, n)
+	if err != nil {
+		return current, reverted, err
+	}
+
+	// Восстанавливаются только поля задачи: снимок удаленной задачи не возвращает ее в корзину
+	reverted = current
+	reverted.Title, reverted.Description, reverted.Date = snapshot.Task.Title, snapshot.Task.Description, snapshot.Task.Date
+	reverted.Repeat, reverted.Time, reverted.Priority = snapshot.Task.Repeat, snapshot.Task.Time, snapshot.Task.Priority
+	reverted.Tags = append([]string{}, snapshot.Task.Tags...)
+	status, err := normalizeStatus(snapshot.Task.Status)
+	if err != nil {
+		return current, reverted, err
+	}
+	if err := checkTransition(current.Status, status); err != nil {
+		return current, reverted, err
+	}
+	setStatus(&reverted, status, time.Now())
+	reverted.Version = n
+	return current, reverted, nil
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// transitions допустимые переходы между статусами задачи.
+// Выполненную или отмененную задачу можно вернуть в работу только через ReopenTask.
+var transitions = map[string][]string{
+	model.StatusTodo:       {model.StatusInProgress, model.StatusBlocked, model.StatusDone, model.StatusCancelled},
+	model.StatusInProgress: {model.StatusTodo, model.StatusBlocked, model.StatusDone, model.StatusCancelled},
+	model.StatusBlocked:    {model.StatusTodo, model.StatusInProgress, model.StatusCancelled},
+	model.StatusDone:       {},
+	model.StatusCancelled:  {},
+}
+
+// legacyStatuses статусы первой версии API
+var legacyStatuses = map[string]string{
+	"":             model.StatusTodo,
+	"Не выполнено": model.StatusTodo,
+	"Выполнено":    model.StatusDone,
+}
+
+// normalizeStatus приводит статус к одному из значений model.Status*
+func normalizeStatus(status string) (string, error) {
+	if s, ok := legacyStatuses[status]; ok {
+		return s, nil
+	}
+	if _, ok := transitions[status]; !ok {
+		return "", taskerror.ErrInvalidStatus
+	}
+	return status, nil
+}
+
+// checkTransition проверяет допустимость перехода задачи из статуса from в статус to
+func checkTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &taskerror.TransitionError{From: from, To: to}
+}
+
+// setStatus переводит задачу в статус и поддерживает время выполнения
+func setStatus(task *model.Task, status string, now time.Time) {
+	if task.Status != status || (status == model.StatusDone && task.CompletedAt == nil) {
+		task.CompletedAt = nil
+		if status == model.StatusDone {
+			completedAt := now.UTC()
+			task.CompletedAt = &completedAt
+		}
+	}
+	task.Status = status
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// GetTaskTree возвращает задачу со всеми подзадачами любой вложенности
+func (service TaskService) GetTaskTree(ctx context.Context, id string) (*model.TaskTree, error) {
+	root, err := service.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := service.taskData.FindSubtasks(ctx, root.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	children := make(map[int64][]model.Task)
+	for _, subtask := range subtasks {
+		subtask.Overdue, subtask.Blocked = isOverdue(subtask, now), isBlocked(subtask)
+		children[*subtask.ParentId] = append(children[*subtask.ParentId], subtask)
+	}
+	tree := buildTree(*root, children)
+	return &tree, nil
+}
+
+// buildTree собирает дерево задачи task из подзадач, сгруппированных по родительской задаче
+func buildTree(task model.Task, children map[int64][]model.Task) model.TaskTree {
+	tree := model.TaskTree{Task: task, Subtasks: []model.TaskTree{}}
+	for _, child := range children[task.Id] {
+		tree.Subtasks = append(tree.Subtasks, buildTree(child, children))
+	}
+	return tree
+}
+
+// checkParent проверяет родительскую задачу task и переносит подзадачу в проект родителя.
+// current - сохраненное состояние задачи или nil при создании задачи.
+// Отсутствие родителя при изменении оставляет прежнего родителя, 0 делает задачу задачей верхнего уровня.
+func (service TaskService) checkParent(ctx context.Context, task *model.Task, current *model.Task) error {
+	if task.ParentId == nil && current != nil {
+		task.ParentId = current.ParentId
+	}
+	if task.ParentId == nil || *task.ParentId == 0 {
+		task.ParentId = nil
+		return nil
+	}
+	parentID := *task.ParentId
+	parent, err := service.taskData.GetTask(ctx, parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return taskerror.ErrInvalidParent
+	}
+	if err != nil {
+		return err
+	}
+
+	if current != nil && (current.ParentId == nil || *current.ParentId != parentID) {
+		// Задача не может стать подзадачей самой себя или своей подзадачи
+		if parentID == current.Id {
+			return taskerror.ErrParentCycle
+		}
+		subtasks, err := service.taskData.FindSubtasks(ctx, current.Id)
+		if err != nil {
+			return err
+		}
+		for _, subtask := range subtasks {
+			if subtask.Id == parentID {
+				return taskerror.ErrParentCycle
+			}
+		}
+	}
+
+	// Подзадача всегда находится в проекте родителя; явно перенести ее в другой проект нельзя
+	moved := task.ProjectId != 0 && (current == nil || task.ProjectId != current.ProjectId)
+	if moved && task.ProjectId != parent.ProjectId {
+		return taskerror.ErrSubtaskProject
+	}
+	task.ProjectId = parent.ProjectId
+	return nil
+}
+
+// completeSubtasks завершает подзадачи выполненной задачи id
+func (service TaskService) completeSubtasks(ctx context.Context, id int64, completedAt *time.Time) error {
+	if completedAt == nil {
+		return nil
+	}
+	_, err := service.taskData.CompleteSubtasks(ctx, id, *completedAt)
+	return err
+}
@@ -0,0 +1,122 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// parseTagID разбирает идентификатор тега из запроса
+func parseTagID(id string) (int64, error) {
+	convId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || convId <= 0 {
+		return 0, taskerror.ErrInvalidTagID
+	}
+	return convId, nil
+}
+
+// tagNotFound заменяет отсутствие строки в базе данных на ошибку ErrNotFoundTag
+func tagNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return taskerror.ErrNotFoundTag
+	}
+	return err
+}
+
+// ListTags возвращает до limit тегов, начинающихся с prefix, от часто используемых к редким
+func (service TaskService) ListTags(ctx context.Context, prefix string, limit int) (*model.TagList, error) {
+	if limit == 0 {
+		limit = settings.TasksListRowsLimit
+	}
+	if limit < 0 || limit > settings.TasksListMaxLimit {
+		return nil, fmt.Errorf("%w: expected 1 - %d", taskerror.ErrInvalidLimit, settings.TasksListMaxLimit)
+	}
+	tags, err := service.taskData.FindTags(ctx, strings.ToLower(strings.TrimSpace(prefix)), limit)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TagList{Tags: tags}, nil
+}
+
+// GetTag возвращает тег по идентификатору
+func (service TaskService) GetTag(ctx context.Context, id string) (*model.Tag, error) {
+	convId, err := parseTagID(id)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := service.taskData.GetTag(ctx, convId)
+	if err != nil {
+		return nil, tagNotFound(err)
+	}
+	return &tag, nil
+}
+
+// CreateTag создает тег и возвращает его ID
+func (service TaskService) CreateTag(ctx context.Context, tag model.Tag) (int64, error) {
+	name, err := service.uniqueTagName(ctx, tag.Name, 0)
+	if err != nil {
+		return 0, err
+	}
+	return service.taskData.InsertTag(ctx, name)
+}
+
+// RenameTag переименовывает тег; новое имя получают и все задачи с этим тегом
+func (service TaskService) RenameTag(ctx context.Context, id string, tag model.Tag) (*model.Tag, error) {
+	convId, err := parseTagID(id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name, err = service.uniqueTagName(ctx, tag.Name, convId); err != nil {
+		return nil, err
+	}
+	tag.Id = convId
+	updated, err := service.taskData.UpdateTag(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, taskerror.ErrNotFoundTag
+	}
+	return service.GetTag(ctx, id)
+}
+
+// DeleteTag удаляет тег и снимает его со всех задач
+func (service TaskService) DeleteTag(ctx context.Context, id string) error {
+	convId, err := parseTagID(id)
+	if err != nil {
+		return err
+	}
+	deleted, err := service.taskData.DeleteTag(ctx, convId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return taskerror.ErrNotFoundTag
+	}
+	return nil
+}
+
+// uniqueTagName проверяет имя тега и что оно не занято другим тегом, кроме тега self
+func (service TaskService) uniqueTagName(ctx context.Context, name string, self int64) (string, error) {
+	name, err := normalizeTag(name)
+	if err != nil {
+		var errs taskerror.FieldErrors
+		errs.Add("name", err)
+		return "", errs.Err()
+	}
+	existing, err := service.taskData.GetTagByName(ctx, name)
+	if err == nil && existing.Id != self {
+		return "", taskerror.ErrTagExists
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return name, nil
+}
@@ -1,17 +1,41 @@
 package task
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"github.com/ZnNr/todo-list/internal/database"
 	"github.com/ZnNr/todo-list/internal/error"
 	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
 	"github.com/ZnNr/todo-list/internal/settings"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// searchDateFormat формат даты в поисковом запросе
+const searchDateFormat = "02.01.2006"
+
+// patchDocument поля задачи, которые можно изменить запросом PATCH
+type patchDocument struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Date        string   `json:"date"`
+	ProjectId   int64    `json:"project_id"`
+	ParentId    *int64   `json:"parent_id"`
+	Time        string   `json:"time"`
+	Priority    int      `json:"priority"`
+	Status      string   `json:"status"`
+	Repeat      string   `json:"repeat"`
+	Tags        []string `json:"tags"`
+}
+
 // TaskService представляет сервис для работы с задачами
 type TaskService struct {
-	taskData *database.TaskData
+	taskData database.TaskStore
 }
 
 // sliceToTasks конвертирует срез задач в структуру TaskList
@@ -22,123 +46,353 @@ func sliceToTasks(list []model.Task) *model.TaskList {
 	return &model.TaskList{Tasks: list}
 }
 
-// Функция convertTask конвертирует и проверяет задачу перед сохранением
-func convertTask(task *model.Task) error {
-	if len(task.Title) == 0 {
-		return taskerror.ErrRequireTitle
-	}
-	// Установка даты по умолчанию, если она не была указана, и проверка формата даты
-	now := time.Now().Format(settings.DateFormat)
-	if len(task.Date) == 0 {
-		task.Date = now
+// notFound заменяет отсутствие строки в базе данных на ошибку ErrNotFoundTask
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return taskerror.ErrNotFoundTask
 	}
-	_, err := time.Parse(settings.DateFormat, task.Date)
-	if err != nil {
-		return err
+	return err
+}
+
+// parseID разбирает идентификатор задачи из запроса
+func parseID(id string) (int64, error) {
+	convId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || convId <= 0 {
+		return 0, taskerror.ErrInvalidID
 	}
-	return nil
+	return convId, nil
 }
 
 // InitTaskService создает новый экземпляр TaskService
-func InitTaskService(taskData *database.TaskData) TaskService {
+func InitTaskService(taskData database.TaskStore) TaskService {
 	return TaskService{taskData: taskData}
 }
 
-// CreateTask Метод создает новую задачу
-func (service TaskService) CreateTask(task model.Task) (int, error) {
-	err := convertTask(&task)
+// CreateTask Метод создает новую задачу.
+// Пустой статус означает todo.
+func (service TaskService) CreateTask(ctx context.Context, task model.Task) (int, error) {
+	err := validateTask(&task)
 	if err != nil {
 		return 0, err
 	}
-	id, err := service.taskData.InsertTask(task)
+	status, err := normalizeStatus(task.Status)
+	if err != nil {
+		return 0, err
+	}
+	if err := service.checkParent(ctx, &task, nil); err != nil {
+		return 0, err
+	}
+	if err := service.checkProject(ctx, task.ProjectId, 0); err != nil {
+		return 0, err
+	}
+	task.Status, task.CompletedAt = "", nil
+	setStatus(&task, status, time.Now())
+
+	id, err := service.taskData.InsertTask(ctx, task)
 	return int(id), err
 }
 
-// UpdateTask обновляет существующую задач
-func (service TaskService) UpdateTask(task model.Task) error {
-	err := convertTask(&task)
+// UpdateTask обновляет существующую задачу, если ее текущая версия удовлетворяет условию cond.
+// Пустой статус оставляет статус задачи без изменений, смена статуса проверяется по transitions.
+func (service TaskService) UpdateTask(ctx context.Context, task model.Task, cond Precondition) error {
+	err := validateTask(&task)
 	if err != nil {
 		return err
 	}
+	if err := service.authorizeTask(ctx, task.Id, writeTasks); err != nil {
+		return err
+	}
 
-	updated, err := service.taskData.UpdateTask(task)
+	current, err := service.taskData.GetTask(ctx, task.Id)
 	if err != nil {
+		return notFound(err)
+	}
+	if err := cond.check(current.Version); err != nil {
 		return err
 	}
-	if !updated {
-		return taskerror.ErrNotFoundTask
+	return service.replaceTask(ctx, current, task, cond)
+}
+
+// PatchTask применяет частичное изменение к сохраненной задаче и возвращает результат.
+// Изменяются только поля patchDocument; итоговая задача проверяется так же, как при UpdateTask.
+func (service TaskService) PatchTask(ctx context.Context, id string, p patch.Patch, cond Precondition) (*model.Task, error) {
+	convId, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorizeTask(ctx, convId, writeTasks); err != nil {
+		return nil, err
+	}
+	current, err := service.taskData.GetTask(ctx, convId)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := cond.check(current.Version); err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(patchDocument{
+		Title:       current.Title,
+		Description: current.Description,
+		Date:        current.Date,
+		ProjectId:   current.ProjectId,
+		ParentId:    current.ParentId,
+		Time:        current.Time,
+		Priority:    current.Priority,
+		Status:      current.Status,
+		Repeat:      current.Repeat,
+		Tags:        append([]string{}, current.Tags...),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = p.Apply(doc); err != nil {
+		return nil, err
+	}
+	var patched patchDocument
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return nil, taskerror.JSONError(err)
+	}
+
+	task := current
+	task.Title, task.Description, task.Date = patched.Title, patched.Description, patched.Date
+	task.Status, task.Repeat = patched.Status, patched.Repeat
+	task.Time, task.Priority = patched.Time, patched.Priority
+	task.ProjectId = patched.ProjectId
+	// Удаленный родитель делает задачу задачей верхнего уровня
+	task.ParentId = patched.ParentId
+	if task.ParentId == nil {
+		task.ParentId = new(int64)
+	}
+	// Удаленный или пустой список тегов снимает с задачи все теги
+	task.Tags = append([]string{}, patched.Tags...)
+	if err := validateTask(&task); err != nil {
+		return nil, err
+	}
+	if err := service.replaceTask(ctx, current, task, cond); err != nil {
+		return nil, err
+	}
+	return service.GetTask(ctx, id)
+}
+
+// replaceTask сохраняет проверенную задачу task вместо current, проверяя смену статуса, родителя и проекта.
+// Пустой статус оставляет статус задачи без изменений. Выполненная задача завершает и свои подзадачи.
+func (service TaskService) replaceTask(ctx context.Context, current, task model.Task, cond Precondition) error {
+	if err := service.checkParent(ctx, &task, &current); err != nil {
+		return err
+	}
+	if err := service.checkProject(ctx, task.ProjectId, current.ProjectId); err != nil {
+		return err
+	}
+	status := current.Status
+	if len(task.Status) > 0 {
+		var err error
+		if status, err = normalizeStatus(task.Status); err != nil {
+			return err
+		}
+	}
+	if err := checkTransition(current.Status, status); err != nil {
+		return err
+	}
+	task.Status, task.CompletedAt = current.Status, current.CompletedAt
+	task.Version = current.Version
+	setStatus(&task, status, time.Now())
+
+	if err := service.saveTask(ctx, task, model.ActionUpdate, cond); err != nil {
+		return err
+	}
+	if status == model.StatusDone && current.Status != model.StatusDone {
+		return service.completeSubtasks(ctx, task.Id, task.CompletedAt)
 	}
 	return nil
 }
 
-// GetTasks возвращает список задач
-func (service TaskService) GetTasks() (*model.TaskList, error) {
-	list, err := service.taskData.GetTasks(settings.TasksListRowsLimit)
+// ListTasks возвращает страницу задач, удовлетворяющих фильтру
+func (service TaskService) ListTasks(ctx context.Context, filter model.TaskFilter) (*model.TaskList, error) {
+	if err := normalizeFilter(&filter); err != nil {
+		return nil, err
+	}
+	list, next, err := service.taskData.FindTasks(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	return sliceToTasks(list), err
+	now := time.Now()
+	for i := range list {
+		list[i].Overdue, list[i].Blocked = isOverdue(list[i], now), isBlocked(list[i])
+	}
+	tasks := sliceToTasks(list)
+	tasks.NextCursor = next
+	return tasks, nil
+}
+
+// ListOverdue возвращает страницу просроченных задач, по умолчанию упорядоченных по приоритету и сроку
+func (service TaskService) ListOverdue(ctx context.Context, filter model.TaskFilter) (*model.TaskList, error) {
+	filter.Overdue, filter.Now = true, time.Now()
+	if len(filter.Sort) == 0 && !database.IsSearchQuery(filter.Search) {
+		filter.Sort = "priority"
+	}
+	return service.ListTasks(ctx, filter)
 }
 
-// GetTasksByStatus возвращает задачи по статусу
-func (service TaskService) GetTasksByStatus(status string) (*model.TaskList, error) {
-	list, err := service.taskData.GetTasksByStatus(status, settings.TasksListRowsLimit)
-	return sliceToTasks(list), err
+// isOverdue проверяет, прошел ли к моменту now срок невыполненной задачи.
+// Задача без времени просрочена со следующего дня после даты.
+func isOverdue(task model.Task, now time.Time) bool {
+	if task.Status == model.StatusDone || task.Status == model.StatusCancelled || len(task.Date) == 0 {
+		return false
+	}
+	today := now.Format(settings.DateFormat)
+	if task.Date != today {
+		return task.Date < today
+	}
+	return len(task.Time) > 0 && task.Time < now.Format(settings.TimeFormat)
 }
 
-// GetTasksByDateAndStatus возвращает задачи по дате и статусу
-func (service TaskService) GetTasksByDateAndStatus(status string, date string) (*model.TaskList, error) {
-	list, err := service.taskData.GetTasksByDateAndStatus(status, date, settings.TasksListRowsLimit)
-	return sliceToTasks(list), err
+// normalizeFilter проверяет параметры выборки и подставляет значения по умолчанию
+func normalizeFilter(filter *model.TaskFilter) error {
+	// Поисковый запрос в виде даты 02.01.2006 означает выборку за эту дату
+	if date, err := time.Parse(searchDateFormat, strings.TrimSpace(filter.Search)); err == nil {
+		filter.Search = ""
+		filter.DateFrom = date.Format(settings.DateFormat)
+		filter.DateTo = filter.DateFrom
+	}
+	// Результаты поиска по умолчанию упорядочены по релевантности
+	if len(filter.Sort) == 0 && database.IsSearchQuery(filter.Search) {
+		filter.Sort, filter.Desc = "relevance", true
+	}
+	if len(filter.Sort) == 0 {
+		filter.Sort = database.DefaultSort
+	}
+	if filter.Limit == 0 {
+		filter.Limit = settings.TasksListRowsLimit
+	}
+	return validateFilter(filter)
 }
 
 // GetTask возвращает задачу по идентификатору
-func (service TaskService) GetTask(id string) (*model.Task, error) {
-	convId, err := strconv.Atoi(id)
+func (service TaskService) GetTask(ctx context.Context, id string) (*model.Task, error) {
+	convId, err := parseID(id)
 	if err != nil {
 		return nil, err
 	}
-	task, err := service.taskData.GetTask(convId)
+	task, err := service.taskData.GetTask(ctx, convId)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
+	task.Overdue, task.Blocked = isOverdue(task, time.Now()), isBlocked(task)
 	return &task, nil
 }
 
-// DeleteTask удаляет задачу по идентификатору
-func (service TaskService) DeleteTask(id string) error {
-	convId, err := strconv.Atoi(id)
+// DeleteTask удаляет задачу по идентификатору, если ее текущая версия удовлетворяет условию cond
+func (service TaskService) DeleteTask(ctx context.Context, id string, cond Precondition) error {
+	convId, err := parseID(id)
 	if err != nil {
 		return err
 	}
-	deleted, err := service.taskData.DeleteTask(convId)
+	if err := service.authorizeTask(ctx, convId, writeTasks); err != nil {
+		return err
+	}
+	task, err := service.taskData.GetTask(ctx, convId)
 	if err != nil {
+		return notFound(err)
+	}
+	if err := cond.check(task.Version); err != nil {
 		return err
 	}
+	deleted, err := service.taskData.DeleteTask(ctx, convId, task.Version)
+	if err != nil {
+		return notFound(err)
+	}
 	if !deleted {
-		return taskerror.ErrNotFoundTask
+		return service.writeConflict(ctx, convId, cond)
 	}
 	return nil
 }
 
-// DoneTask помечает задачу как выполненную
-func (service TaskService) DoneTask(id string) error {
-	convId, err := strconv.Atoi(id)
+// DoneTask помечает задачу как выполненную вместе со всеми невыполненными подзадачами.
+// Повторяющаяся задача не завершается, а переносится на следующую дату по правилу repeat
+// и возвращается в статус todo; ее подзадачи при этом не меняются.
+func (service TaskService) DoneTask(ctx context.Context, id string) error {
+	convId, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := service.authorizeTask(ctx, convId, writeTasks); err != nil {
+		return err
+	}
+
+	task, err := service.taskData.GetTask(ctx, convId)
 	if err != nil {
+		return notFound(err)
+	}
+	if err := checkTransition(task.Status, model.StatusDone); err != nil {
 		return err
 	}
 
-	task, err := service.taskData.GetTask(convId)
+	now := time.Now()
+	if len(task.Repeat) > 0 {
+		task.Date, err = NextDate(now, task.Date, task.Repeat)
+		if err != nil {
+			return err
+		}
+		setStatus(&task, model.StatusTodo, now)
+	} else {
+		setStatus(&task, model.StatusDone, now)
+	}
+
+	if err := service.saveTask(ctx, task, model.ActionDone, nil); err != nil {
+		return err
+	}
+	if task.Status != model.StatusDone {
+		return nil
+	}
+	return service.completeSubtasks(ctx, task.Id, task.CompletedAt)
+}
+
+// ReopenTask возвращает выполненную или отмененную задачу в статус todo
+func (service TaskService) ReopenTask(ctx context.Context, id string) error {
+	convId, err := parseID(id)
 	if err != nil {
 		return err
 	}
+	if err := service.authorizeTask(ctx, convId, writeTasks); err != nil {
+		return err
+	}
 
-	updated, err := service.taskData.UpdateTask(task)
+	task, err := service.taskData.GetTask(ctx, convId)
+	if err != nil {
+		return notFound(err)
+	}
+	if task.Status != model.StatusDone && task.Status != model.StatusCancelled {
+		return &taskerror.TransitionError{From: task.Status, To: model.StatusTodo}
+	}
+	setStatus(&task, model.StatusTodo, time.Now())
+
+	return service.saveTask(ctx, task, model.ActionReopen, nil)
+}
+
+// saveTask сохраняет уже проверенную задачу, если ее версия в базе данных не изменилась с момента чтения.
+// action - действие, под которым изменение попадает в журнал.
+func (service TaskService) saveTask(ctx context.Context, task model.Task, action string, cond Precondition) error {
+	updated, err := service.taskData.UpdateTask(ctx, task, action)
 	if err != nil {
 		return err
 	}
 	if !updated {
-		return taskerror.ErrNotFoundTask
+		return service.writeConflict(ctx, task.Id, cond)
 	}
 	return nil
 }
+
+// writeConflict объясняет, почему не удалось изменить задачу прочитанной версии:
+// задача удалена, не выполнено условие запроса или задачу изменил другой запрос
+func (service TaskService) writeConflict(ctx context.Context, id int64, cond Precondition) error {
+	if _, err := service.taskData.GetTask(ctx, id); err != nil {
+		return notFound(err)
+	}
+	if cond != nil {
+		return taskerror.ErrPreconditionFailed
+	}
+	return taskerror.ErrConcurrentUpdate
+}
@@ -0,0 +1,87 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+)
+
+// ListTrash возвращает страницу задач из корзины, удовлетворяющих фильтру
+func (service TaskService) ListTrash(ctx context.Context, filter model.TaskFilter) (*model.TaskList, error) {
+	filter.Trash = true
+	return service.ListTasks(ctx, filter)
+}
+
+// RestoreTask возвращает задачу из корзины
+func (service TaskService) RestoreTask(ctx context.Context, id string) error {
+	convId, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := service.authorizeTask(ctx, convId, writeTasks); err != nil {
+		return err
+	}
+	restored, err := service.taskData.RestoreTask(ctx, convId)
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return taskerror.ErrNotInTrash
+	}
+	return nil
+}
+
+// PurgeTask окончательно удаляет задачу из корзины
+func (service TaskService) PurgeTask(ctx context.Context, id string) error {
+	convId, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := service.authorizeTask(ctx, convId, writeTasks); err != nil {
+		return err
+	}
+	purged, err := service.taskData.PurgeTask(ctx, convId)
+	if err != nil {
+		return err
+	}
+	if !purged {
+		return taskerror.ErrNotInTrash
+	}
+	return nil
+}
+
+// EmptyTrash окончательно удаляет из корзины все задачи пользователя и возвращает их количество.
+// Задачи других участников общих проектов остаются в корзине.
+func (service TaskService) EmptyTrash(ctx context.Context) (int64, error) {
+	return service.taskData.PurgeTrash(ctx, time.Now())
+}
+
+// RunTrashRetention раз в interval удаляет из корзин всех пользователей задачи, пролежавшие в них
+// дольше retention, пока не будет отменен ctx. Нулевой retention отключает очистку.
+func (service TaskService) RunTrashRetention(ctx context.Context, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	ctx = database.WithAllOwners(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := service.taskData.PurgeTrash(ctx, time.Now().Add(-retention))
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("Error purging trash: %v", err)
+		case purged > 0:
+			log.Printf("Purged %d tasks from trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
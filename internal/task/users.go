@@ -0,0 +1,141 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginPattern допустимый логин пользователя; логины хранятся в нижнем регистре
+var loginPattern = regexp.MustCompile(`^[a-z0-9._-]{3,32}$`)
+
+// dummyHash хеш, с которым сравнивается пароль при входе под несуществующим логином,
+// чтобы по времени ответа нельзя было узнать, есть ли такой пользователь
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// normalizeLogin приводит логин к виду, в котором он хранится
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// checkPassword проверяет длину нового пароля
+func checkPassword(errs *taskerror.FieldErrors, field, password string) {
+	if len(password) < settings.UserPasswordMinLength || len(password) > settings.UserPasswordMaxLength {
+		errs.Add(field, taskerror.ErrWeakPassword)
+	}
+}
+
+// hashPassword возвращает хеш пароля bcrypt
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// RegisterUser регистрирует пользователя и возвращает его ID. Пароль хранится только в виде хеша bcrypt.
+func (service TaskService) RegisterUser(ctx context.Context, registration model.Registration) (int64, error) {
+	var errs taskerror.FieldErrors
+	login, name := normalizeLogin(registration.Login), strings.TrimSpace(registration.Name)
+	if !loginPattern.MatchString(login) {
+		errs.Add("login", taskerror.ErrInvalidLogin)
+	}
+	checkPassword(&errs, "password", registration.Password)
+	checkLength(&errs, "name", name, settings.UserNameMaxLength)
+	if err := errs.Err(); err != nil {
+		return 0, err
+	}
+
+	// Занятый логин отклоняется до вычисления хеша пароля; одновременную регистрацию с тем же логином
+	// отклонит ограничение UNIQUE при вставке
+	_, err := service.taskData.GetUserByLogin(ctx, login)
+	if err == nil {
+		return 0, taskerror.ErrLoginTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	hash, err := hashPassword(registration.Password)
+	if err != nil {
+		return 0, err
+	}
+	return service.taskData.InsertUser(ctx, model.User{Login: login, Name: name, PasswordHash: hash})
+}
+
+// Authenticate проверяет логин и пароль и возвращает пользователя.
+// Неизвестный логин и неверный пароль неразличимы: в обоих случаях возвращается ErrInvalidCredentials.
+func (service TaskService) Authenticate(ctx context.Context, credentials model.Credentials) (*model.User, error) {
+	user, err := service.taskData.GetUserByLogin(ctx, normalizeLogin(credentials.Login))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	// У пользователя по умолчанию нет пароля, войти под ним по логину нельзя
+	if err != nil || len(user.PasswordHash) == 0 {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(credentials.Password))
+		return nil, taskerror.ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credentials.Password)) != nil {
+		return nil, taskerror.ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
+// GetUser возвращает пользователя по ID. Пользователя, которого нет, нельзя считать вошедшим:
+// для него возвращается ErrUnauthorized.
+func (service TaskService) GetUser(ctx context.Context, id int64) (*model.User, error) {
+	user, err := service.taskData.GetUser(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, taskerror.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetProfile возвращает пользователя, от имени которого выполняется запрос
+func (service TaskService) GetProfile(ctx context.Context) (*model.User, error) {
+	return service.GetUser(ctx, database.OwnerFrom(ctx))
+}
+
+// UpdateProfile изменяет имя и, если указан новый пароль, пароль пользователя, от имени которого
+// выполняется запрос. Для смены пароля нужен текущий пароль; после смены выданные токены недействительны.
+func (service TaskService) UpdateProfile(ctx context.Context, update model.ProfileUpdate) (*model.User, error) {
+	user, err := service.GetProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs taskerror.FieldErrors
+	user.Name = strings.TrimSpace(update.Name)
+	checkLength(&errs, "name", user.Name, settings.UserNameMaxLength)
+	if len(update.Password) > 0 {
+		checkPassword(&errs, "password", update.Password)
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(update.CurrentPassword)) != nil {
+			errs.Add("current_password", taskerror.ErrWrongPassword)
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(update.Password) > 0 {
+		if user.PasswordHash, err = hashPassword(update.Password); err != nil {
+			return nil, err
+		}
+	}
+	updated, err := service.taskData.UpdateUser(ctx, *user)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, taskerror.ErrUnauthorized
+	}
+	return service.GetProfile(ctx)
+}
@@ -0,0 +1,158 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+)
+
+// validateTask проверяет задачу перед сохранением и возвращает все найденные ошибки сразу.
+// Пустая дата заменяется текущей, пустой приоритет - приоритетом по умолчанию,
+// статус проверяется, только если он указан.
+func validateTask(task *model.Task) error {
+	var errs taskerror.FieldErrors
+	if len(strings.TrimSpace(task.Title)) == 0 {
+		errs.Add("title", taskerror.ErrRequireTitle)
+	}
+	checkLength(&errs, "title", task.Title, settings.TaskTitleMaxLength)
+	checkLength(&errs, "description", task.Description, settings.TaskDescriptionMaxLength)
+
+	if len(task.Date) == 0 {
+		task.Date = time.Now().Format(settings.DateFormat)
+	}
+	if err := validateDate(task.Date); err != nil {
+		errs.Add("date", err)
+	}
+	if len(task.Time) > 0 {
+		if _, err := time.Parse(settings.TimeFormat, task.Time); err != nil || len(task.Time) != len(settings.TimeFormat) {
+			errs.Add("time", taskerror.ErrInvalidDueTime)
+		}
+	}
+	if task.ProjectId < 0 {
+		errs.Add("project_id", taskerror.ErrInvalidProject)
+	}
+	if task.ParentId != nil && *task.ParentId < 0 {
+		errs.Add("parent_id", taskerror.ErrInvalidParent)
+	}
+	if task.Priority == 0 {
+		task.Priority = model.PriorityLowest
+	}
+	if task.Priority < model.PriorityHighest || task.Priority > model.PriorityLowest {
+		errs.Add("priority", taskerror.ErrInvalidPriority)
+	}
+	if len(task.Repeat) > 0 {
+		if err := ValidateRepeat(task.Repeat); err != nil {
+			errs.Add("repeat", err)
+		}
+	}
+	if len(task.Status) > 0 {
+		if _, err := normalizeStatus(task.Status); err != nil {
+			errs.Add("status", err)
+		}
+	}
+	if task.Tags != nil {
+		task.Tags = normalizeTags(&errs, "tags", task.Tags)
+		if len(task.Tags) > settings.TaskTagsMaxCount {
+			errs.Add("tags", fmt.Errorf("%w: at most %d tags", taskerror.ErrInvalidTag, settings.TaskTagsMaxCount))
+		}
+	}
+	return errs.Err()
+}
+
+// normalizeTag приводит имя тега к нижнему регистру без пробелов по краям и проверяет его
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) == 0 || strings.Contains(name, ",") {
+		return "", taskerror.ErrInvalidTag
+	}
+	if n := utf8.RuneCountInString(name); n > settings.TagNameMaxLength {
+		return "", fmt.Errorf("%w: tag must be at most %d characters, got %d", taskerror.ErrTooLong, settings.TagNameMaxLength, n)
+	}
+	return name, nil
+}
+
+// normalizeTags приводит имена тегов поля field к единому виду и убирает повторы.
+// Результат не nil, даже если список пуст.
+func normalizeTags(errs *taskerror.FieldErrors, field string, names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := []string{}
+	for _, name := range names {
+		tag, err := normalizeTag(name)
+		if err != nil {
+			errs.Add(field, err)
+			continue
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// validateFilter проверяет параметры выборки списка задач, уже дополненные значениями по умолчанию
+func validateFilter(filter *model.TaskFilter) error {
+	var errs taskerror.FieldErrors
+	for i, status := range filter.Statuses {
+		var err error
+		if filter.Statuses[i], err = normalizeStatus(status); err != nil {
+			errs.Add("status", err)
+			break
+		}
+	}
+	if !database.IsSortField(filter.Sort) {
+		errs.Add("sort", taskerror.ErrInvalidSort)
+	}
+	if filter.Limit < 0 || filter.Limit > settings.TasksListMaxLimit {
+		errs.Add("limit", fmt.Errorf("%w: expected 1 - %d", taskerror.ErrInvalidLimit, settings.TasksListMaxLimit))
+	}
+	if len(filter.Tags) > 0 {
+		filter.Tags = normalizeTags(&errs, "tag", filter.Tags)
+	}
+	dateFrom := validDate(&errs, "date_from", filter.DateFrom)
+	dateTo := validDate(&errs, "date_to", filter.DateTo)
+	if dateFrom && dateTo && filter.DateFrom > filter.DateTo {
+		errs.Add("date_from", taskerror.ErrInvalidDateRange)
+	}
+	return errs.Err()
+}
+
+// validDate проверяет необязательную дату поля field и сообщает, указана ли корректная дата
+func validDate(errs *taskerror.FieldErrors, field, date string) bool {
+	if len(date) == 0 {
+		return false
+	}
+	if _, err := time.Parse(settings.DateFormat, date); err != nil {
+		errs.Add(field, taskerror.ErrInvalidDate)
+		return false
+	}
+	return true
+}
+
+// validateDate проверяет формат даты задачи и ее попадание в диапазон
+// от settings.TaskDateMin до settings.TaskDateMax
+func validateDate(date string) error {
+	if _, err := time.Parse(settings.DateFormat, date); err != nil {
+		return taskerror.ErrInvalidDate
+	}
+	// Даты в формате YYYYMMDD упорядочены так же, как строки
+	if date < settings.TaskDateMin || date > settings.TaskDateMax {
+		return fmt.Errorf("%w: expected %s - %s", taskerror.ErrDateOutOfRange, settings.TaskDateMin, settings.TaskDateMax)
+	}
+	return nil
+}
+
+// checkLength проверяет, что значение поля не длиннее max символов
+func checkLength(errs *taskerror.FieldErrors, field, value string, max int) {
+	if n := utf8.RuneCountInString(value); n > max {
+		errs.Add(field, fmt.Errorf("%w: %s must be at most %d characters, got %d", taskerror.ErrTooLong, field, max, n))
+	}
+}
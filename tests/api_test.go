@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/handlers"
+	"github.com/ZnNr/todo-list/internal/router"
+	taskservice "github.com/ZnNr/todo-list/internal/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPI запускает тестовый сервер с маршрутизатором приложения и временной базой данных
+func newAPI(t *testing.T) *httptest.Server {
+	handlers.TaskServiceInstance = taskservice.InitTaskService(openStore(t))
+	srv := httptest.NewServer(router.NewRouter())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// apiRequest выполняет запрос к тестовому серверу и возвращает ответ с прочитанным телом
+func apiRequest(t *testing.T, srv *httptest.Server, method, path string, body any, headers ...string) (*http.Response, []byte) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func TestRESTTasks(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Созвон", "date": "20240129"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var created struct{ Id int64 }
+	require.NoError(t, json.Unmarshal(body, &created))
+	location := resp.Header.Get("Location")
+	assert.Equal(t, "/api/v1/tasks/"+itoa(created.Id), location)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Созвон")
+
+	resp, _ = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Созвон в 16:00", "date": "20240129"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, location, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = apiRequest(t, srv, http.MethodGet, location, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/task", map[string]any{"title": "Старый клиент"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+	var created struct{ Id int64 }
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/task?id="+itoa(created.Id), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, "{}", string(body))
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/auth"
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/handlers"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/router"
+	taskservice "github.com/ZnNr/todo-list/internal/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createAPIKey создает ключ API с разрешениями scopes и возвращает его вместе с самим ключом
+func createAPIKey(t *testing.T, srv *httptest.Server, cookie, name string, scopes ...string) model.APIKey {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/users/me/keys", map[string]any{"name": name, "scopes": scopes},
+		"Cookie", cookie)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	var key model.APIKey
+	require.NoError(t, json.Unmarshal(body, &key))
+	assert.Equal(t, "/api/v1/users/me/keys/"+itoa(key.Id), resp.Header.Get("Location"))
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	return key
+}
+
+func TestAPIKeys(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "qwerty", time.Hour)
+	alice, bob := createUser(t, srv, "alice"), createUser(t, srv, "bob")
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/users/me/keys",
+		map[string]any{"name": " ", "scopes": []string{"tasks:admin"}, "expires_at": time.Now().Add(-time.Hour)}, "Cookie", alice)
+	problem := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "validation_failed")
+	assert.Equal(t, map[string]string{"name": "name_required", "scopes": "invalid_scope", "expires_at": "invalid_expiry"},
+		fieldCodes(problem))
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/users/me/keys", map[string]any{"name": "ci"}, "Cookie", alice)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_scope")
+
+	ci := createAPIKey(t, srv, alice, "ci", model.ScopeTasksRead, model.ScopeTasksWrite, model.ScopeTasksRead)
+	assert.Equal(t, []string{model.ScopeTasksRead, model.ScopeTasksWrite}, ci.Scopes)
+	assert.True(t, strings.HasPrefix(ci.Prefix, auth.APIKeyPrefix), ci.Prefix)
+	assert.True(t, strings.HasPrefix(ci.Key, ci.Prefix), ci.Key)
+	reader := createAPIKey(t, srv, alice, "dashboard", model.ScopeTasksRead)
+	writer := createAPIKey(t, srv, alice, "import", model.ScopeTasksWrite)
+
+	// Ключ выполняет запросы от имени владельца в пределах своих разрешений
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Из CI", "date": "20240126"},
+		"Authorization", "Bearer "+ci.Key)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/history", nil, "Authorization", "bearer "+reader.Key)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "alice", eventList(t, resp, body).Events[0].Actor)
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/done", nil, "Authorization", "Bearer "+reader.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "insufficient_scope")
+	resp, body = apiRequest(t, srv, http.MethodGet, location, nil, "Authorization", "Bearer "+writer.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "insufficient_scope")
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/done", nil, "Authorization", "Bearer "+writer.Key)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+
+	// Другим пользователям задачи владельца ключа не видны
+	resp, body = apiRequest(t, srv, http.MethodGet, location, nil, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+
+	// Разрешения ключа относятся только к задачам, в том числе к устаревшим маршрутам
+	resp, body = apiRequest(t, srv, http.MethodGet, "/tasks", nil, "Authorization", "Bearer "+reader.Key)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	for _, path := range []string{"/api/v1/projects", "/api/v1/tags", "/api/v1/trash", "/api/v1/audit"} {
+		resp, body = apiRequest(t, srv, http.MethodGet, path, nil, "Authorization", "Bearer "+ci.Key)
+		requireProblem(t, resp, body, http.StatusForbidden, "insufficient_scope")
+	}
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash", nil, "Authorization", "Bearer "+ci.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "insufficient_scope")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Из CI"}, "Authorization", "Bearer "+ci.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "insufficient_scope")
+
+	// Ключом нельзя изменить учетную запись, принять приглашение или управлять ключами,
+	// а в списке ключей сами ключи не возвращаются
+	resp, body = apiRequest(t, srv, http.MethodPut, "/api/v1/users/me", map[string]any{"password": "новый пароль"},
+		"Authorization", "Bearer "+ci.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "session_required")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/invitations", nil, "Authorization", "Bearer "+ci.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "session_required")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me/keys", nil, "Authorization", "Bearer "+ci.Key)
+	requireProblem(t, resp, body, http.StatusForbidden, "session_required")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me/keys", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.NotContains(t, string(body), ci.Key)
+	var list model.APIKeyList
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Keys, 3)
+	assert.Equal(t, []string{"import", "dashboard", "ci"}, []string{list.Keys[0].Name, list.Keys[1].Name, list.Keys[2].Name})
+	assert.Equal(t, ci.Prefix, list.Keys[2].Prefix)
+	assert.NotNil(t, list.Keys[2].LastUsedAt, "время использования ключа отмечается")
+
+	// Токен тоже принимается в заголовке Authorization
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me/keys", nil, "Authorization",
+		"Bearer "+strings.TrimPrefix(alice, "token="))
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	// Отозванный ключ сразу перестает действовать; чужой ключ отозвать нельзя
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/api/v1/users/me/keys/"+itoa(ci.Id), nil, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusNotFound, "api_key_not_found")
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/api/v1/users/me/keys/"+itoa(ci.Id), nil, "Cookie", alice)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Authorization", "Bearer "+ci.Key)
+	requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me/keys/"+itoa(ci.Id), nil, "Cookie", alice)
+	requireProblem(t, resp, body, http.StatusNotFound, "api_key_not_found")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me/keys/x", nil, "Cookie", alice)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_id")
+}
+
+func TestAPIKeyExpiry(t *testing.T) {
+	store := openStore(t)
+	handlers.TaskServiceInstance = taskservice.InitTaskService(store)
+	srv := httptest.NewServer(router.NewRouter())
+	t.Cleanup(srv.Close)
+	enableAuth(t, "qwerty", time.Hour)
+
+	// Срок действия ключа при создании должен быть в будущем, поэтому просроченный ключ создается в хранилище
+	secret, prefix, err := auth.NewAPIKey()
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Minute)
+	_, err = store.InsertAPIKey(database.WithOwner(context.Background(), model.DefaultUserId), model.APIKey{
+		Name: "old", Prefix: prefix, KeyHash: auth.HashAPIKey(secret), Scopes: []string{model.ScopeTasksRead}, ExpiresAt: &expired,
+	})
+	require.NoError(t, err)
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Authorization", "Bearer "+secret)
+	requireProblem(t, resp, body, http.StatusUnauthorized, "api_key_expired")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Authorization", "Bearer "+auth.APIKeyPrefix+"unknown")
+	requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventList возвращает страницу журнала изменений
+func eventList(t *testing.T, resp *http.Response, body []byte) model.TaskEventList {
+	t.Helper()
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list model.TaskEventList
+	require.NoError(t, json.Unmarshal(body, &list))
+	return list
+}
+
+// actions возвращает действия записей журнала по порядку
+func actions(list model.TaskEventList) []string {
+	var result []string
+	for _, event := range list.Events {
+		result = append(result, event.Action)
+	}
+	return result
+}
+
+func TestTaskHistory(t *testing.T) {
+	srv := newAPI(t)
+	start := time.Now().UTC().Add(-time.Second)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "date": "20240301"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "Отчет за март", "description": "Черновик"},
+		"Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	// Неудачное изменение не попадает в журнал
+	resp, body = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет", "date": "20240301"},
+		"If-Match", `"1"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	for _, step := range []string{"/done", "/reopen"} {
+		resp, _ = apiRequest(t, srv, http.MethodPost, location+step, nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+	resp, _ = apiRequest(t, srv, http.MethodDelete, location, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// История удаленной задачи остается доступной
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/history", nil)
+	history := eventList(t, resp, body)
+	require.Equal(t, []string{"delete", "reopen", "done", "update", "create"}, actions(history))
+
+	created := history.Events[4]
+	assert.Equal(t, "127.0.0.1", created.Actor)
+	assert.Equal(t, model.FieldChange{From: nil, To: "Отчет"}, created.Changes["title"])
+	assert.Equal(t, model.FieldChange{From: nil, To: "todo"}, created.Changes["status"])
+	assert.False(t, created.CreatedAt.Before(start))
+
+	assert.Equal(t, map[string]model.FieldChange{
+		"title":       {From: "Отчет", To: "Отчет за март"},
+		"description": {From: nil, To: "Черновик"},
+	}, history.Events[3].Changes, "в журнал попадают только измененные поля")
+	assert.Equal(t, model.FieldChange{From: "todo", To: "done"}, history.Events[2].Changes["status"])
+	assert.Contains(t, history.Events[0].Changes, "deleted_at")
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/history?action=done,reopen&limit=1", nil)
+	page := eventList(t, resp, body)
+	require.Equal(t, []string{"reopen"}, actions(page))
+	require.NotEmpty(t, page.NextCursor)
+	query := url.Values{"action": {"done", "reopen"}, "limit": {"1"}, "cursor": {page.NextCursor}}
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/history?"+query.Encode(), nil)
+	page = eventList(t, resp, body)
+	assert.Equal(t, []string{"done"}, actions(page))
+	assert.Empty(t, page.NextCursor)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks/999/history", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+}
+
+func TestAuditFeed(t *testing.T) {
+	srv := newAPI(t)
+	start := time.Now().UTC().Add(-time.Second)
+
+	for _, title := range []string{"Купить молоко", "Позвонить маме"} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": title})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		resp, _ = apiRequest(t, srv, http.MethodDelete, resp.Header.Get("Location"), nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+	resp, _ := apiRequest(t, srv, http.MethodDelete, "/api/v1/trash", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/audit", nil)
+	feed := eventList(t, resp, body)
+	assert.Equal(t, []string{"purge", "purge", "delete", "create", "delete", "create"}, actions(feed))
+	assert.Equal(t, model.FieldChange{From: "Купить молоко", To: nil}, feed.Events[1].Changes["title"],
+		"окончательное удаление сохраняет последнее состояние задачи")
+
+	query := url.Values{"action": {"create"}, "from": {start.Format(time.RFC3339)}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?"+query.Encode(), nil)
+	assert.Equal(t, []string{"create", "create"}, actions(eventList(t, resp, body)))
+
+	query = url.Values{"to": {start.Format(time.RFC3339)}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?"+query.Encode(), nil)
+	assert.Empty(t, eventList(t, resp, body).Events)
+
+	query = url.Values{"action": {"archive"}, "from": {"вчера"}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?"+query.Encode(), nil)
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_time")
+	assert.Equal(t, map[string]string{"from": "invalid_time"}, fieldCodes(p))
+
+	query = url.Values{"action": {"archive"}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?"+query.Encode(), nil)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_action")
+
+	query = url.Values{"from": {"2024-03-02T00:00:00Z"}, "to": {"2024-03-01T00:00:00Z"}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?"+query.Encode(), nil)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_time_range")
+}
+
+func TestAuditAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "todolist.db")
+	store, err := database.NewTaskData("sqlite://" + path)
+	require.NoError(t, err)
+	defer store.CloseDb()
+	require.NoError(t, store.Migrate(ctx))
+
+	_, err = store.InsertTask(database.WithActor(ctx, "cron"), model.Task{Date: "20240301", Title: "Отчет", Status: model.StatusTodo})
+	require.NoError(t, err)
+	events, _, err := store.FindEvents(ctx, model.EventFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "cron", events[0].Actor)
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.ExecContext(ctx, "UPDATE task_events SET actor = 'someone'")
+	assert.ErrorContains(t, err, "append-only")
+	_, err = db.ExecContext(ctx, "DELETE FROM task_events")
+	assert.ErrorContains(t, err, "append-only")
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/auth"
+	"github.com/ZnNr/todo-list/internal/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enableAuth включает вход по паролю password на время теста
+func enableAuth(t *testing.T, password string, ttl time.Duration) {
+	t.Helper()
+	authenticator, err := auth.New(password, []byte("test-secret"), ttl)
+	require.NoError(t, err)
+	handlers.AuthInstance = authenticator
+	t.Cleanup(func() { handlers.AuthInstance = nil })
+}
+
+// signIn выполняет вход и возвращает токен из cookie token
+func signIn(t *testing.T, srv *httptest.Server, password string) string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/signin", map[string]any{"password": password})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var result struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(body, &result))
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == handlers.TokenCookie {
+			assert.Equal(t, result.Token, cookie.Value)
+			assert.True(t, cookie.HttpOnly)
+			return cookie.Value
+		}
+	}
+	t.Fatal("нет cookie token")
+	return ""
+}
+
+func TestSignIn(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "qwerty", time.Hour)
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil)
+	requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/signin", map[string]any{"password": "12345"})
+	requireProblem(t, resp, body, http.StatusUnauthorized, "invalid_password")
+
+	token := signIn(t, srv, "qwerty")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Cookie", "token="+token)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/projects", nil, "Cookie", "token="+token+"x")
+	requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+
+	// Устаревшие маршруты сообщают об ошибке в прежнем формате, а вычисление даты доступно без входа
+	resp, body = apiRequest(t, srv, http.MethodGet, "/tasks", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, string(body), `"error"`)
+	resp, _ = apiRequest(t, srv, http.MethodGet, "/api/nextdate?now=20240126&date=20240126&repeat=d+1", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// После смены пароля выданные токены недействительны
+	enableAuth(t, "new-password", time.Hour)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Cookie", "token="+token)
+	requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+}
+
+func TestTokenExpiry(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "qwerty", -time.Second)
+
+	token := signIn(t, srv, "qwerty")
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Cookie", "token="+token)
+	requireProblem(t, resp, body, http.StatusUnauthorized, "token_expired")
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taskView возвращает задачи из представления задач path, например /api/v1/tasks/actionable
+func taskView(t *testing.T, srv *httptest.Server, path string) []string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list model.TaskList
+	require.NoError(t, json.Unmarshal(body, &list))
+	return titles(list.Tasks)
+}
+
+func TestDependencies(t *testing.T) {
+	srv := newAPI(t)
+
+	walls := createSubtask(t, srv, "Выровнять стены", 0)
+	paint := createSubtask(t, srv, "Покрасить стены", 0)
+	furniture := createSubtask(t, srv, "Расставить мебель", 0)
+	wallsID, paintID, furnitureID := getTask(t, srv, walls).Id, getTask(t, srv, paint).Id, getTask(t, srv, furniture).Id
+
+	for _, dep := range []string{paint + "/dependencies/" + itoa(wallsID), furniture + "/dependencies/" + itoa(paintID)} {
+		resp, body := apiRequest(t, srv, http.MethodPut, dep, nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	}
+	resp, _ := apiRequest(t, srv, http.MethodPut, paint+"/dependencies/"+itoa(wallsID), nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode, "повторное добавление зависимости ничего не меняет")
+
+	painting := getTask(t, srv, paint)
+	assert.True(t, painting.Blocked)
+	assert.Equal(t, []int64{wallsID}, painting.BlockedBy)
+	assert.False(t, getTask(t, srv, walls).Blocked)
+
+	resp, body := apiRequest(t, srv, http.MethodGet, paint+"/dependencies", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var deps model.TaskDependencies
+	require.NoError(t, json.Unmarshal(body, &deps))
+	assert.Equal(t, []string{"Выровнять стены"}, titles(deps.BlockedBy))
+	assert.Equal(t, []string{"Расставить мебель"}, titles(deps.Blocks))
+
+	// Зависимость, замыкающая цикл, в том числе через другие задачи, отклоняется
+	for _, dep := range []string{walls + "/dependencies/" + itoa(furnitureID), walls + "/dependencies/" + itoa(wallsID)} {
+		resp, body = apiRequest(t, srv, http.MethodPut, dep, nil)
+		requireProblem(t, resp, body, http.StatusConflict, "dependency_cycle")
+	}
+	resp, body = apiRequest(t, srv, http.MethodPut, walls+"/dependencies/999", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+	resp, body = apiRequest(t, srv, http.MethodDelete, walls+"/dependencies/"+itoa(paintID), nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "dependency_not_found")
+
+	assert.Equal(t, []string{"Выровнять стены"}, taskView(t, srv, "/api/v1/tasks/actionable"))
+	resp, _ = apiRequest(t, srv, http.MethodPost, walls+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.False(t, getTask(t, srv, paint).Blocked, "выполненная задача больше не блокирует")
+	assert.Equal(t, []string{"Покрасить стены"}, taskView(t, srv, "/api/v1/tasks/actionable"))
+
+	resp, body = apiRequest(t, srv, http.MethodDelete, furniture+"/dependencies/"+itoa(paintID), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	assert.Empty(t, getTask(t, srv, furniture).BlockedBy)
+	assert.Equal(t, []string{"Покрасить стены", "Расставить мебель"}, taskView(t, srv, "/api/v1/tasks/actionable"))
+}
+
+func TestDependencyPlan(t *testing.T) {
+	srv := newAPI(t)
+
+	tasks := map[string]string{}
+	for _, task := range []map[string]any{
+		{"title": "Собрать вещи", "priority": 2},
+		{"title": "Купить билеты", "priority": 1},
+		{"title": "Вызвать такси", "priority": 3},
+		{"title": "Полить цветы", "priority": 4},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		tasks[task["title"].(string)] = resp.Header.Get("Location")
+	}
+	id := func(title string) string { return itoa(getTask(t, srv, tasks[title]).Id) }
+
+	// Такси ждет вещей и билетов, билеты ждут цветов, несмотря на более высокий приоритет
+	for task, blocker := range map[string]string{"Купить билеты": "Полить цветы", "Вызвать такси": "Собрать вещи"} {
+		resp, body := apiRequest(t, srv, http.MethodPut, tasks[task]+"/dependencies/"+id(blocker), nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	}
+	resp, _ := apiRequest(t, srv, http.MethodPut, tasks["Вызвать такси"]+"/dependencies/"+id("Купить билеты"), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	assert.Equal(t, []string{"Собрать вещи", "Полить цветы", "Купить билеты", "Вызвать такси"},
+		taskView(t, srv, "/api/v1/tasks/plan"))
+	assert.Equal(t, []string{"Собрать вещи", "Полить цветы"}, taskView(t, srv, "/api/v1/tasks/actionable"))
+
+	// Блокирующая задача в корзине не блокирует, а окончательное удаление убирает ее зависимости
+	flowersID := id("Полить цветы")
+	resp, _ = apiRequest(t, srv, http.MethodDelete, tasks["Полить цветы"], nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, []string{"Купить билеты", "Собрать вещи"}, taskView(t, srv, "/api/v1/tasks/actionable"))
+	resp, _ = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash/"+flowersID, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body := apiRequest(t, srv, http.MethodGet, tasks["Купить билеты"]+"/dependencies", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var deps model.TaskDependencies
+	require.NoError(t, json.Unmarshal(body, &deps))
+	assert.Empty(t, deps.BlockedBy)
+}
+
+// requireETagChanged проверяет, что представление задачи изменилось: ответ на GET с прежним ETag
+// в If-None-Match - 200, а не 304 - и возвращает новый ETag
+func requireETagChanged(t *testing.T, srv *httptest.Server, location, tag string) string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodGet, location, nil, "If-None-Match", tag)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	require.NotEqual(t, tag, resp.Header.Get("ETag"))
+	return resp.Header.Get("ETag")
+}
+
+func TestDependencyETag(t *testing.T) {
+	srv := newAPI(t)
+	walls := createSubtask(t, srv, "Выровнять стены", 0)
+	paint := createSubtask(t, srv, "Покрасить стены", 0)
+	wallsID := getTask(t, srv, walls).Id
+	resp, _ := apiRequest(t, srv, http.MethodGet, paint, nil)
+	tag := resp.Header.Get("ETag")
+
+	// Зависимости и выполнение блокирующей задачи меняют blocked_by, поэтому и ETag заблокированной задачи
+	resp, body := apiRequest(t, srv, http.MethodPut, paint+"/dependencies/"+itoa(wallsID), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	tag = requireETagChanged(t, srv, paint, tag)
+	resp, _ = apiRequest(t, srv, http.MethodPost, walls+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	tag = requireETagChanged(t, srv, paint, tag)
+	resp, _ = apiRequest(t, srv, http.MethodPost, walls+"/reopen", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	tag = requireETagChanged(t, srv, paint, tag)
+	resp, _ = apiRequest(t, srv, http.MethodDelete, paint+"/dependencies/"+itoa(wallsID), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	tag = requireETagChanged(t, srv, paint, tag)
+
+	// Запись с ETag, полученным до изменения зависимостей, отклоняется
+	resp, _ = apiRequest(t, srv, http.MethodPut, paint+"/dependencies/"+itoa(wallsID), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodPut, paint, map[string]any{"title": "Покрасить стены", "date": "20240301"},
+		"If-Match", tag)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+}
+
+func TestDependencyPlanLimit(t *testing.T) {
+	limit := settings.TaskPlanLimit
+	settings.TaskPlanLimit = 2
+	t.Cleanup(func() { settings.TaskPlanLimit = limit })
+	srv := newAPI(t)
+
+	for _, task := range []map[string]any{
+		{"title": "Полить цветы", "priority": 4},
+		{"title": "Купить билеты", "priority": 1},
+		{"title": "Собрать вещи", "priority": 2},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+	assert.Equal(t, []string{"Купить билеты", "Собрать вещи"}, taskView(t, srv, "/api/v1/tasks/plan"))
+}
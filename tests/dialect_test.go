@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/error"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openPostgresStore открывает хранилище в базе PostgreSQL из TODO_TEST_POSTGRES_DSN с чистой схемой:
+// все примененные миграции откатываются и применяются заново. Без TODO_TEST_POSTGRES_DSN тест пропускается.
+func openPostgresStore(t *testing.T) *database.TaskData {
+	dsn := os.Getenv("TODO_TEST_POSTGRES_DSN")
+	if len(dsn) == 0 {
+		t.Skip("TODO_TEST_POSTGRES_DSN is not set")
+	}
+	store, err := database.NewTaskData(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.CloseDb() })
+	_, err = store.MigrateDown(context.Background(), math.MaxInt)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+// TestDialectStore выполняет одни и те же запросы на SQLite и, если задана TODO_TEST_POSTGRES_DSN, на PostgreSQL,
+// который разбирает и типизирует запросы строже SQLite
+func TestDialectStore(t *testing.T) {
+	for _, dialect := range []struct {
+		name string
+		open func(t *testing.T) *database.TaskData
+	}{
+		{"sqlite", openStore},
+		{"postgres", openPostgresStore},
+	} {
+		t.Run(dialect.name, func(t *testing.T) {
+			store := dialect.open(t)
+			t.Run("dependencies", func(t *testing.T) { checkDependencyStore(t, store) })
+			t.Run("users", func(t *testing.T) { checkUserStore(t, store) })
+			t.Run("members", func(t *testing.T) { checkMemberStore(t, store) })
+		})
+	}
+}
+
+// checkDependencyStore проверяет добавление зависимостей и поиск циклов рекурсивным запросом
+func checkDependencyStore(t *testing.T, store *database.TaskData) {
+	ctx := context.Background()
+	ids := make([]int64, 3)
+	for i := range ids {
+		id, err := store.InsertTask(ctx, model.Task{Date: "20240126", Title: "Задача", Status: model.StatusTodo})
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	added, err := store.AddDependency(ctx, ids[0], ids[1])
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = store.AddDependency(ctx, ids[1], ids[2])
+	require.NoError(t, err)
+	assert.True(t, added)
+	_, err = store.AddDependency(ctx, ids[2], ids[0])
+	assert.ErrorIs(t, err, taskerror.ErrDependencyCycle)
+	dependencies, err := store.FindDependencies(ctx, ids[1])
+	require.NoError(t, err)
+	assert.Len(t, dependencies.BlockedBy, 1)
+	assert.Len(t, dependencies.Blocks, 1)
+}
+
+// checkUserStore проверяет, что занятый логин, который не нашла проверка перед вставкой,
+// отклоняется ограничением UNIQUE с ошибкой ErrLoginTaken, а не внутренней ошибкой
+func checkUserStore(t *testing.T, store *database.TaskData) {
+	ctx := context.Background()
+	_, err := store.InsertUser(ctx, model.User{Login: "racer", PasswordHash: "hash"})
+	require.NoError(t, err)
+	_, err = store.InsertUser(ctx, model.User{Login: "racer", PasswordHash: "other"})
+	assert.ErrorIs(t, err, taskerror.ErrLoginTaken)
+}
+
+// checkMemberStore проверяет, что приглашение пользователю, у которого уже есть роль в проекте,
+// не принимается с ошибкой ErrAlreadyMember и остается, а не нарушает первичный ключ участников
+func checkMemberStore(t *testing.T, store *database.TaskData) {
+	owner, err := store.InsertUser(context.Background(), model.User{Login: "keeper", PasswordHash: "hash"})
+	require.NoError(t, err)
+	member, err := store.InsertUser(context.Background(), model.User{Login: "guest", PasswordHash: "hash"})
+	require.NoError(t, err)
+	ownerCtx, memberCtx := database.WithOwner(context.Background(), owner), database.WithOwner(context.Background(), member)
+	project, err := store.InsertProject(ownerCtx, model.Project{Name: "Общий"})
+	require.NoError(t, err)
+
+	invite := func(user int64) int64 {
+		id, err := store.InsertInvitation(ownerCtx, model.Invitation{ProjectId: project, UserId: user, Role: model.RoleEditor})
+		require.NoError(t, err)
+		return id
+	}
+	accepted, err := store.AcceptInvitation(ownerCtx, invite(owner))
+	assert.ErrorIs(t, err, taskerror.ErrAlreadyMember)
+	assert.False(t, accepted)
+
+	accepted, err = store.AcceptInvitation(memberCtx, invite(member))
+	require.NoError(t, err)
+	assert.True(t, accepted)
+	again := invite(member)
+	_, err = store.AcceptInvitation(memberCtx, again)
+	assert.ErrorIs(t, err, taskerror.ErrAlreadyMember)
+	// Чужое приглашение не принимается и не раскрывает, состоит ли адресат в проекте
+	accepted, err = store.AcceptInvitation(ownerCtx, again)
+	require.NoError(t, err)
+	assert.False(t, accepted)
+}
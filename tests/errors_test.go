@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	taskerror "github.com/ZnNr/todo-list/internal/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problem тело ответа application/problem+json
+type problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail"`
+	Instance string                 `json:"instance"`
+	Code     string                 `json:"code"`
+	Errors   []taskerror.FieldError `json:"errors"`
+}
+
+// requireProblem проверяет код и тип ответа с ошибкой и возвращает его тело
+func requireProblem(t *testing.T, resp *http.Response, body []byte, status int, code string) problem {
+	t.Helper()
+	require.Equal(t, status, resp.StatusCode, string(body))
+	assert.Equal(t, taskerror.ProblemContentType, resp.Header.Get("Content-Type"))
+	var p problem
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, status, p.Status)
+	assert.Equal(t, code, p.Code)
+	assert.Equal(t, "urn:todo-list:problem:"+code, p.Type)
+	assert.Equal(t, http.StatusText(status), p.Title)
+	return p
+}
+
+func TestProblemResponses(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks/999", nil)
+	p := requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+	assert.Equal(t, "/api/v1/tasks/999", p.Instance)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks/abc", nil)
+	p = requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_id")
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "id", p.Errors[0].Field)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": ""})
+	p = requireProblem(t, resp, body, http.StatusUnprocessableEntity, "title_required")
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "title", p.Errors[0].Field)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "date": "31.01.2024"})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_date")
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/tasks", strings.NewReader(`{"title":`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	raw, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer raw.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, raw.StatusCode)
+	assert.Equal(t, taskerror.ProblemContentType, raw.Header.Get("Content-Type"))
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "status": "cancelled"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodPost, resp.Header.Get("Location")+"/done", nil)
+	p = requireProblem(t, resp, body, http.StatusConflict, "illegal_transition")
+	assert.Contains(t, p.Detail, "cancelled -> done")
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks?order=up", nil)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_order")
+
+	// Устаревшие маршруты сохраняют прежний формат ошибки
+	resp, body = apiRequest(t, srv, http.MethodGet, "/task?id=999", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.JSONEq(t, `{"error": "not found task"}`, string(body))
+}
+
+func TestErrorKinds(t *testing.T) {
+	wrapped := taskerror.ErrMalformedBody.Wrap(errors.New("unexpected EOF"))
+	assert.ErrorIs(t, wrapped, taskerror.ErrMalformedBody)
+	assert.Equal(t, http.StatusBadRequest, taskerror.From(wrapped).Kind.Status())
+
+	transition := &taskerror.TransitionError{From: "done", To: "todo"}
+	assert.ErrorIs(t, transition, taskerror.ErrIllegalTransition)
+	assert.Equal(t, http.StatusConflict, taskerror.From(transition).Kind.Status())
+
+	internal := taskerror.NewProblem(errors.New("disk I/O error"), "/api/v1/tasks")
+	assert.Equal(t, http.StatusInternalServerError, internal.Status)
+	assert.NotContains(t, internal.Detail, "disk")
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimisticConcurrency(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "date": "20240301"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+
+	resp, _ = apiRequest(t, srv, http.MethodGet, location, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.EqualValues(t, 1, getTask(t, srv, location).Version)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location, nil, "If-None-Match", `"1"`)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	resp, _ = apiRequest(t, srv, http.MethodGet, location, nil, "If-None-Match", `"0", W/"1"`)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode, "If-None-Match сравнивает теги слабо")
+
+	// Первый участник сохраняет изменения
+	resp, body = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет за март", "date": "20240301"},
+		"If-Match", `"1"`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	// Второй участник редактировал версию 1 и получает отказ вместо перезаписи
+	resp, body = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет за апрель", "date": "20240301"},
+		"If-Match", `"1"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "Отчет за апрель"},
+		"Content-Type", patch.MergePatchType, "If-Match", `"1"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "Отчет за апрель"},
+		"Content-Type", patch.MergePatchType, "If-Match", `W/"2"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет за апрель", "date": "20240301"},
+		"If-None-Match", "*")
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location, nil, "If-Match", `"1"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+	assert.Equal(t, "Отчет за март", getTask(t, srv, location).Title)
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "Отчет за апрель"},
+		"Content-Type", patch.MergePatchType, "If-Match", `"2"`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.EqualValues(t, 4, getTask(t, srv, location).Version)
+
+	resp, body = apiRequest(t, srv, http.MethodDelete, location, nil, "If-Match", `"3"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, location, nil, "If-Match", "*")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, body = apiRequest(t, srv, http.MethodDelete, location, nil, "If-Match", "*")
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+}
@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userID возвращает ID пользователя с токеном из cookie
+func userID(t *testing.T, srv *httptest.Server, cookie string) int64 {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/users/me", nil, "Cookie", cookie)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var user model.User
+	require.NoError(t, json.Unmarshal(body, &user))
+	return user.Id
+}
+
+// invite приглашает пользователя login в проект с ролью role и возвращает адрес приглашения
+func invite(t *testing.T, srv *httptest.Server, cookie, project, login, role string) string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodPost, project+"/invitations", map[string]any{"login": login, "role": role},
+		"Cookie", cookie)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	return resp.Header.Get("Location")
+}
+
+func TestProjectSharing(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "", time.Hour)
+	alice, bob, carol := createUser(t, srv, "alice"), createUser(t, srv, "bob"), createUser(t, srv, "carol")
+	bobID := userID(t, srv, bob)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Ремонт"}, "Cookie", alice)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	project := resp.Header.Get("Location")
+	var created struct {
+		Id int64 `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	projectID := created.Id
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks",
+		map[string]any{"title": "Покрасить стены", "date": "20240301", "project_id": projectID}, "Cookie", alice)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	walls := resp.Header.Get("Location")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Личная задача", "date": "20240301"},
+		"Cookie", alice)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+
+	// Приглашения проверяются до создания
+	for _, c := range []struct {
+		login, role string
+		status      int
+		code        string
+	}{
+		{"nobody", "viewer", http.StatusUnprocessableEntity, "unknown_user"},
+		{"bob", "owner", http.StatusUnprocessableEntity, "invalid_role"},
+		{"alice", "viewer", http.StatusConflict, "project_owner"},
+	} {
+		resp, body = apiRequest(t, srv, http.MethodPost, project+"/invitations", map[string]any{"login": c.login, "role": c.role},
+			"Cookie", alice)
+		requireProblem(t, resp, body, c.status, c.code)
+	}
+	invitation := invite(t, srv, alice, project, "Bob", "viewer")
+	resp, body = apiRequest(t, srv, http.MethodPost, project+"/invitations", map[string]any{"login": "bob", "role": "editor"},
+		"Cookie", alice)
+	requireProblem(t, resp, body, http.StatusConflict, "already_invited")
+
+	// До принятия приглашения Боб не видит проект и его задачи, а приглашение видят только он и владелец проекта
+	for _, path := range []string{project, walls, project + "/members"} {
+		resp, body = apiRequest(t, srv, http.MethodGet, path, nil, "Cookie", bob)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "%s: %s", path, body)
+	}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/invitations", nil, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var invitations model.InvitationList
+	require.NoError(t, json.Unmarshal(body, &invitations))
+	require.Len(t, invitations.Invitations, 1)
+	assert.Equal(t, model.Invitation{Id: invitations.Invitations[0].Id, ProjectId: projectID, ProjectName: "Ремонт",
+		Login: "bob", Role: "viewer", InvitedBy: "alice", CreatedAt: invitations.Invitations[0].CreatedAt}, invitations.Invitations[0])
+	resp, _ = apiRequest(t, srv, http.MethodGet, invitation, nil, "Cookie", alice)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodGet, invitation, nil, "Cookie", carol)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodPost, invitation+"/accept", nil, "Cookie", carol)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "чужое приглашение принять нельзя")
+	resp, body = apiRequest(t, srv, http.MethodPost, invitation+"/accept", nil, "Cookie", bob)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+
+	// Наблюдатель видит задачи проекта, но не может их изменять: 403, а не 404
+	resp, body = apiRequest(t, srv, http.MethodGet, project, nil, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var shared model.Project
+	require.NoError(t, json.Unmarshal(body, &shared))
+	assert.Equal(t, "viewer", shared.Role)
+	assert.Equal(t, int64(1), shared.TaskCount)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Покрасить стены")
+	assert.NotContains(t, string(body), "Личная задача", "задачи Входящих владельца проекта остаются личными")
+	for _, request := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPut, walls, map[string]any{"title": "Покрасить потолок", "date": "20240301"}},
+		{http.MethodPost, walls + "/done", nil},
+		{http.MethodDelete, walls, nil},
+		{http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Новая", "date": "20240301", "project_id": projectID}},
+		{http.MethodPut, project, map[string]any{"name": "Мой ремонт"}},
+		{http.MethodPost, project + "/invitations", map[string]any{"login": "carol", "role": "viewer"}},
+	} {
+		resp, body = apiRequest(t, srv, request.method, request.path, request.body, "Cookie", bob)
+		requireProblem(t, resp, body, http.StatusForbidden, "forbidden")
+	}
+	// Для остальных пользователей задачи проекта по-прежнему не существуют
+	resp, _ = apiRequest(t, srv, http.MethodPut, walls, map[string]any{"title": "Взлом", "date": "20240301"}, "Cookie", carol)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Редактор изменяет и создает задачи проекта; изменения видны владельцу проекта в его журнале
+	resp, body = apiRequest(t, srv, http.MethodPut, project+"/members/"+itoa(bobID), map[string]any{"role": "editor"}, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusForbidden, "forbidden")
+	resp, body = apiRequest(t, srv, http.MethodPut, project+"/members/"+itoa(bobID), map[string]any{"role": "editor"}, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var member model.ProjectMember
+	require.NoError(t, json.Unmarshal(body, &member))
+	assert.Equal(t, model.ProjectMember{UserId: bobID, Login: "bob", Role: "editor", CreatedAt: member.CreatedAt}, member)
+
+	resp, body = apiRequest(t, srv, http.MethodPut, walls, map[string]any{"title": "Покрасить стены в белый", "date": "20240301",
+		"tags": []string{"ремонт"}}, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks",
+		map[string]any{"title": "Купить краску", "date": "20240301", "project_id": projectID}, "Cookie", bob)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	paint := resp.Header.Get("Location")
+	resp, body = apiRequest(t, srv, http.MethodGet, project+"/tasks", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Купить краску")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?action=update", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"actor":"bob"`)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tags", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "ремонт", "задачи отмечаются тегами их владельца")
+
+	// Администратор изменяет проект, но удалить его может только владелец
+	resp, _ = apiRequest(t, srv, http.MethodPut, project+"/members/"+itoa(bobID), map[string]any{"role": "admin"}, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodPut, project, map[string]any{"name": "Ремонт кухни"}, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodDelete, project, nil, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusForbidden, "forbidden")
+	resp, body = apiRequest(t, srv, http.MethodDelete, project+"/members/"+itoa(userID(t, srv, alice)), nil, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusConflict, "project_owner")
+
+	// Администратор приглашает, приглашение можно отозвать или отклонить
+	revoked := invite(t, srv, bob, project, "carol", "viewer")
+	resp, body = apiRequest(t, srv, http.MethodGet, project+"/invitations", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"invited_by":"bob"`)
+	resp, _ = apiRequest(t, srv, http.MethodDelete, project+"/invitations/"+strings.TrimPrefix(revoked, "/api/v1/invitations/"), nil, "Cookie", alice)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodPost, revoked+"/accept", nil, "Cookie", carol)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	declined := invite(t, srv, alice, project, "carol", "editor")
+	resp, _ = apiRequest(t, srv, http.MethodPost, declined+"/decline", nil, "Cookie", carol)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/invitations", nil, "Cookie", carol)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"invitations":[]}`, string(body))
+
+	resp, body = apiRequest(t, srv, http.MethodGet, project+"/members", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var members model.ProjectMemberList
+	require.NoError(t, json.Unmarshal(body, &members))
+	require.Len(t, members.Members, 2)
+	assert.Equal(t, []string{"alice", "bob"}, []string{members.Members[0].Login, members.Members[1].Login})
+	assert.Equal(t, []string{"owner", "admin"}, []string{members.Members[0].Role, members.Members[1].Role})
+
+	// Покинув проект, Боб теряет доступ ко всем задачам проекта, в том числе к созданным им самим,
+	// а они остаются в проекте и доступны его владельцу
+	resp, _ = apiRequest(t, srv, http.MethodDelete, project+"/members/"+itoa(bobID), nil, "Cookie", bob)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodGet, walls, nil, "Cookie", bob)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodGet, project, nil, "Cookie", bob)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodPut, paint, map[string]any{"title": "Купить краску", "date": "20240302"}, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+	resp, _ = apiRequest(t, srv, http.MethodDelete, paint, nil, "Cookie", bob)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit", nil, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, string(body), `"task_id":`+strings.TrimPrefix(paint, "/api/v1/tasks/")+`,`)
+	resp, _ = apiRequest(t, srv, http.MethodGet, paint, nil, "Cookie", alice)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
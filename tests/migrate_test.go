@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	dbfile := filepath.Join(t.TempDir(), "todolist.db")
+
+	store, err := database.NewTaskData(dbfile)
+	require.NoError(t, err)
+	defer store.CloseDb()
+
+	applied, err := store.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, applied)
+
+	again, err := store.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	states, err := store.MigrationStatus(ctx)
+	require.NoError(t, err)
+	for _, s := range states {
+		assert.NotNil(t, s.AppliedAt, "миграция %d не применена", s.Version)
+	}
+
+	reverted, err := store.MigrateDown(ctx, len(applied))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(applied))
+
+	db, err := sqlx.Connect("sqlite", dbfile)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var tables int
+	require.NoError(t, db.Get(&tables, `SELECT count(*) FROM sqlite_master WHERE name = 'todolist'`))
+	assert.Zero(t, tables)
+
+	_, err = store.MigrateUp(ctx, 0)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE schema_migrations SET checksum = 'tampered' WHERE version = 1`)
+	require.NoError(t, err)
+
+	_, err = store.MigrateUp(ctx, 0)
+	assert.ErrorIs(t, err, database.ErrChecksumMismatch)
+}
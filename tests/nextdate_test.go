@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	taskservice "github.com/ZnNr/todo-list/internal/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nextDate struct {
+	date   string
+	repeat string
+	want   string
+}
+
+var nextDates = []nextDate{
+	{"20240126", "", ""},
+	{"20240126", "k 34", ""},
+	{"20240126", "ooo", ""},
+	{"15000156", "", ""},
+	{"ooo", "", ""},
+	{"16890220", "y", "20240220"},
+	{"20250701", "y", "20260701"},
+	{"20240101", "y", "20250101"},
+	{"20231231", "y", "20241231"},
+	{"20240229", "y", "20250301"},
+	{"20240301", "y", "20250301"},
+	{"20240113", "d", ""},
+	{"20240113", "d 7", "20240127"},
+	{"20240120", "d 20", "20240209"},
+	{"20240202", "d 30", "20240303"},
+	{"20240320", "d 401", ""},
+	{"20231225", "d 12", "20240130"},
+	{"20240228", "d 1", "20240229"},
+	{"20231106", "m 13", "20240213"},
+	{"20240120", "m 40,11,19", ""},
+	{"20240116", "m 16,5", "20240205"},
+	{"20240126", "m 25,26,7", "20240207"},
+	{"20240409", "m 31", "20240531"},
+	{"20240329", "m 10,17 12,8,1", "20240810"},
+	{"20230311", "m 07,19 05,6", "20240507"},
+	{"20230311", "m 1 1,2", "20240201"},
+	{"20240127", "m -1", "20240131"},
+	{"20240222", "m -2", "20240228"},
+	{"20240222", "m -2,-3", ""},
+	{"20240326", "m -1,-2", "20240330"},
+	{"20240201", "m -1,18", "20240218"},
+	{"20240125", "w 1,2,3", "20240129"},
+	{"20240126", "w 7", "20240128"},
+	{"20230126", "w 4,5", "20240201"},
+	{"20230226", "w 8,4,5", ""},
+	{"20240101", "m 30 2", ""},
+}
+
+func TestNextDate(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+	for _, v := range nextDates {
+		got, err := taskservice.NextDate(now, v.date, v.repeat)
+		if len(v.want) == 0 {
+			assert.Error(t, err, "ожидается ошибка для %v", v)
+			continue
+		}
+		assert.NoError(t, err, "%v", v)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+}
+
+func TestNextDateAPI(t *testing.T) {
+	srv := newAPI(t)
+
+	query := url.Values{"now": {"20240126"}, "date": {"20240113"}, "repeat": {"d 7"}}
+	resp, err := srv.Client().Get(srv.URL + "/api/nextdate?" + query.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "20240127", string(body))
+
+	query.Set("repeat", "d 401")
+	resp, err = srv.Client().Get(srv.URL + "/api/nextdate?" + query.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestDoneRecurringTask(t *testing.T) {
+	srv := newAPI(t)
+
+	now := time.Now()
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{
+		"title":  "Проверить работу /done",
+		"date":   now.Format("20060102"),
+		"repeat": "d 3",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+
+	for i := 0; i < 3; i++ {
+		resp, _ = apiRequest(t, srv, http.MethodPost, location+"/done", nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		var task model.Task
+		_, body = apiRequest(t, srv, http.MethodGet, location, nil)
+		require.NoError(t, json.Unmarshal(body, &task))
+		now = now.AddDate(0, 0, 3)
+		assert.Equal(t, now.Format("20060102"), task.Date)
+		assert.NotEqual(t, model.StatusDone, task.Status)
+	}
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Свести баланс"})
+	location = resp.Header.Get("Location")
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	var task model.Task
+	_, body = apiRequest(t, srv, http.MethodGet, location, nil)
+	require.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, model.StatusDone, task.Status)
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Плохое правило", "repeat": "d 0"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
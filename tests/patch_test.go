@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPatchOperations(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"baz": "qux", "foo": "bar"}`},
+		{`{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo": ["bar", "qux", "baz"]}`},
+		{`{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": ["abc"]}]`, `{"foo": ["bar", ["abc"]]}`},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo": "bar"}`},
+		{`{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo": ["bar", "baz"]}`},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": "boo"}]`, `{"baz": "boo", "foo": "bar"}`},
+		{`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`},
+		{`{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			`{"foo": ["all", "cows", "eat", "grass"]}`},
+		{`{"a": {"b": 1}}`, `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "replace", "path": "/c/b", "value": 2}]`,
+			`{"a": {"b": 1}, "c": {"b": 2}}`},
+		{`{"/": 1, "m~n": 2}`, `[{"op": "test", "path": "/~1", "value": 1}, {"op": "remove", "path": "/m~0n"}]`, `{"/": 1}`},
+	}
+	for _, test := range tests {
+		p, err := patch.ParseJSONPatch([]byte(test.patch))
+		require.NoError(t, err, test.patch)
+		got, err := p.Apply([]byte(test.doc))
+		require.NoError(t, err, test.patch)
+		assert.JSONEq(t, test.want, string(got), test.patch)
+	}
+
+	for _, invalid := range []string{
+		`{"op": "add"}`,
+		`[{"op": "jump", "path": "/a"}]`,
+		`[{"op": "add", "path": "/a"}]`,
+		`[{"op": "add", "path": "a", "value": 1}]`,
+		`[{"op": "move", "from": "/a", "path": "/a/b"}]`,
+	} {
+		_, err := patch.ParseJSONPatch([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+
+	p, err := patch.ParseJSONPatch([]byte(`[{"op": "remove", "path": "/missing"}]`))
+	require.NoError(t, err)
+	_, err = p.Apply([]byte(`{"a": 1}`))
+	assert.Error(t, err)
+}
+
+func TestMergePatch(t *testing.T) {
+	p, err := patch.ParseMergePatch([]byte(`{"a": "z", "c": {"f": null}, "e": [1]}`))
+	require.NoError(t, err)
+	got, err := p.Apply([]byte(`{"a": "b", "c": {"d": "e", "f": "g"}, "e": {"x": 1}}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "z", "c": {"d": "e"}, "e": [1]}`, string(got))
+}
+
+func TestPatchTask(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{
+		"title":       "Отчет",
+		"description": "Квартальный",
+		"date":        "20240301",
+		"repeat":      "d 7",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "Годовой отчет", "repeat": nil},
+		"Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	task := getTask(t, srv, location)
+	assert.Equal(t, "Годовой отчет", task.Title)
+	assert.Equal(t, "Квартальный", task.Description)
+	assert.Equal(t, "20240301", task.Date, "дата не должна сбрасываться на сегодня")
+	assert.Empty(t, task.Repeat)
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, []map[string]any{
+		{"op": "test", "path": "/status", "value": "todo"},
+		{"op": "replace", "path": "/status", "value": "in_progress"},
+		{"op": "copy", "from": "/title", "path": "/description"},
+	}, "Content-Type", patch.JSONPatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	task = getTask(t, srv, location)
+	assert.Equal(t, model.StatusInProgress, task.Status)
+	assert.Equal(t, "Годовой отчет", task.Description)
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, []map[string]any{
+		{"op": "test", "path": "/status", "value": "todo"},
+		{"op": "replace", "path": "/title", "value": "Не применится"},
+	}, "Content-Type", patch.JSONPatchType)
+	requireProblem(t, resp, body, http.StatusConflict, "patch_test_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, []map[string]any{
+		{"op": "replace", "path": "/color", "value": "red"},
+	}, "Content-Type", patch.JSONPatchType)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "patch_path_not_found")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "", "date": "2024"},
+		"Content-Type", patch.MergePatchType)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "validation_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"id": 7},
+		"Content-Type", patch.MergePatchType)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "unknown_field")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"title": "Отчет"})
+	requireProblem(t, resp, body, http.StatusUnsupportedMediaType, "unsupported_patch_type")
+	assert.Contains(t, resp.Header.Get("Accept-Patch"), patch.MergePatchType)
+
+	assert.Equal(t, "Годовой отчет", getTask(t, srv, location).Title, "ошибочные запросы не изменяют задачу")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, "/api/v1/tasks/999", map[string]any{"title": "Отчет"},
+		"Content-Type", patch.MergePatchType)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+}
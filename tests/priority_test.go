@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// titles возвращает заголовки задач по порядку
+func titles(tasks []model.Task) []string {
+	var result []string
+	for _, task := range tasks {
+		result = append(result, task.Title)
+	}
+	return result
+}
+
+func TestTaskPriority(t *testing.T) {
+	srv := newAPI(t)
+
+	for _, task := range []map[string]any{
+		{"title": "Обычная", "date": "20240302"},
+		{"title": "Срочная вечером", "date": "20240302", "time": "18:00", "priority": 1},
+		{"title": "Срочная утром", "date": "20240302", "time": "09:30", "priority": 1},
+		{"title": "Срочная без времени", "date": "20240302", "priority": 1},
+		{"title": "Важная раньше", "date": "20240301", "priority": 2},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+
+	want := []string{"Срочная утром", "Срочная вечером", "Срочная без времени", "Важная раньше", "Обычная"}
+	code, list := listTasks(t, srv, url.Values{"sort": {"priority"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, want, titles(list.Tasks))
+	assert.Equal(t, model.PriorityLowest, list.Tasks[4].Priority, "приоритет по умолчанию - P4")
+	assert.Equal(t, "09:30", list.Tasks[0].Time)
+
+	// Постраничный обход сохраняет порядок составного ключа
+	var paged []string
+	query := url.Values{"sort": {"priority"}, "limit": {"2"}}
+	for {
+		code, page := listTasks(t, srv, query)
+		require.Equal(t, http.StatusOK, code)
+		paged = append(paged, titles(page.Tasks)...)
+		if len(page.NextCursor) == 0 {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+	assert.Equal(t, want, paged)
+
+	code, list = listTasks(t, srv, url.Values{"sort": {"due"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Важная раньше", "Срочная утром", "Срочная вечером"}, titles(list.Tasks[:3]))
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "priority": 5, "time": "9:30"})
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "validation_failed")
+	assert.Equal(t, map[string]string{"priority": "invalid_priority", "time": "invalid_due_time"}, fieldCodes(p))
+}
+
+func TestOverdueTasks(t *testing.T) {
+	srv := newAPI(t)
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1).Format(settings.DateFormat)
+	tomorrow := now.AddDate(0, 0, 1).Format(settings.DateFormat)
+
+	for _, task := range []map[string]any{
+		{"title": "Вчерашняя", "date": yesterday},
+		{"title": "Вчерашняя срочная", "date": yesterday, "time": "23:59", "priority": 1},
+		{"title": "Вчерашняя выполненная", "date": yesterday, "status": "done"},
+		{"title": "Завтрашняя", "date": tomorrow},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks/overdue", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var overdue model.TaskList
+	require.NoError(t, json.Unmarshal(body, &overdue))
+	assert.Equal(t, []string{"Вчерашняя срочная", "Вчерашняя"}, titles(overdue.Tasks))
+	for _, task := range overdue.Tasks {
+		assert.True(t, task.Overdue, task.Title)
+	}
+
+	code, list := listTasks(t, srv, url.Values{"sort": {"title"}})
+	require.Equal(t, http.StatusOK, code)
+	flags := map[string]bool{}
+	for _, task := range list.Tasks {
+		flags[task.Title] = task.Overdue
+	}
+	assert.Equal(t, map[string]bool{
+		"Вчерашняя":             true,
+		"Вчерашняя срочная":     true,
+		"Вчерашняя выполненная": false,
+		"Завтрашняя":            false,
+	}, flags)
+}
+
+func TestOverdueByTime(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+
+	for _, task := range []model.Task{
+		{Date: "20240301", Time: "09:00", Title: "Утром"},
+		{Date: "20240301", Time: "18:00", Title: "Вечером"},
+		{Date: "20240301", Title: "В течение дня"},
+		{Date: "20240229", Title: "Вчера", Status: model.StatusCancelled},
+	} {
+		if len(task.Status) == 0 {
+			task.Status = model.StatusTodo
+		}
+		_, err := store.InsertTask(ctx, task)
+		require.NoError(t, err)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	tasks, _, err := store.FindTasks(ctx, model.TaskFilter{Overdue: true, Now: now, Sort: "due"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Утром"}, titles(tasks))
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// projectTasks возвращает заголовки задач проекта в порядке проекта
+func projectTasks(t *testing.T, srv *httptest.Server, project string) []string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodGet, project+"/tasks", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list model.TaskList
+	require.NoError(t, json.Unmarshal(body, &list))
+	return titles(list.Tasks)
+}
+
+func TestProjects(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/projects", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list model.ProjectList
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Projects, 1)
+	assert.Equal(t, "Inbox", list.Projects[0].Name)
+	inbox := "/api/v1/projects/" + itoa(model.InboxProjectId)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": " Дом ", "description": "Дела по дому"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	home := resp.Header.Get("Location")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Дом"})
+	requireProblem(t, resp, body, http.StatusConflict, "project_exists")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": " "})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "name_required")
+
+	var ids []int64
+	for _, title := range []string{"Купить молоко", "Вынести мусор", "Полить цветы"} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": title, "project_id": 2})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		ids = append(ids, getTask(t, srv, resp.Header.Get("Location")).Id)
+	}
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	report := resp.Header.Get("Location")
+	assert.Equal(t, int64(model.InboxProjectId), getTask(t, srv, report).ProjectId, "задача без проекта попадает во Входящие")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "project_id": 99})
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_project")
+	assert.Equal(t, map[string]string{"project_id": "invalid_project"}, fieldCodes(p))
+
+	assert.Equal(t, []string{"Купить молоко", "Вынести мусор", "Полить цветы"}, projectTasks(t, srv, home))
+	resp, body = apiRequest(t, srv, http.MethodGet, home, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var project model.Project
+	require.NoError(t, json.Unmarshal(body, &project))
+	assert.Equal(t, "Дом", project.Name)
+	assert.Equal(t, int64(3), project.TaskCount)
+
+	// Перечисленные задачи встают в начало проекта, остальные сохраняют порядок
+	resp, body = apiRequest(t, srv, http.MethodPut, home+"/order", map[string]any{"tasks": []int64{ids[2], ids[1]}})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"Полить цветы", "Вынести мусор", "Купить молоко"}, projectTasks(t, srv, home))
+
+	resp, body = apiRequest(t, srv, http.MethodPut, home+"/order", map[string]any{"tasks": []int64{ids[0], ids[0]}})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_task_order")
+	resp, body = apiRequest(t, srv, http.MethodPut, inbox+"/order", map[string]any{"tasks": []int64{ids[0]}})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_task_order")
+
+	// Перенесенная задача становится последней в новом проекте
+	location := "/api/v1/tasks/" + itoa(ids[2])
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"project_id": model.InboxProjectId},
+		"Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"Отчет", "Полить цветы"}, projectTasks(t, srv, inbox))
+	assert.Equal(t, []string{"Вынести мусор", "Купить молоко"}, projectTasks(t, srv, home))
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/history?action=update", nil)
+	history := eventList(t, resp, body)
+	require.Len(t, history.Events, 1)
+	assert.Equal(t, model.FieldChange{From: float64(2), To: float64(model.InboxProjectId)}, history.Events[0].Changes["project_id"])
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/projects/99", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "project_not_found")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/projects/99/tasks", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "project_not_found")
+}
+
+func TestArchiveProject(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Ремонт"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	project := resp.Header.Get("Location")
+	for _, task := range []map[string]any{{"title": "Купить краску", "project_id": 2}, {"title": "Отчет"}} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, project+"/archive", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	code, list := listTasks(t, srv, url.Values{})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Отчет"}, titles(list.Tasks), "задачи архивного проекта скрыты из общего списка")
+	assert.Equal(t, []string{"Купить краску"}, projectTasks(t, srv, project))
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/projects?archived=true", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var archived model.ProjectList
+	require.NoError(t, json.Unmarshal(body, &archived))
+	require.Len(t, archived.Projects, 1)
+	assert.NotNil(t, archived.Projects[0].ArchivedAt)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Купить кисти", "project_id": 2})
+	requireProblem(t, resp, body, http.StatusConflict, "project_archived")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects/1/archive", nil)
+	requireProblem(t, resp, body, http.StatusConflict, "inbox_project")
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/api/v1/projects/1", nil)
+	requireProblem(t, resp, body, http.StatusConflict, "inbox_project")
+	resp, body = apiRequest(t, srv, http.MethodDelete, project, nil)
+	requireProblem(t, resp, body, http.StatusConflict, "project_not_empty")
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, project+"/unarchive", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	code, list = listTasks(t, srv, url.Values{})
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Tasks, 2)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Пустой"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	empty := resp.Header.Get("Location")
+	resp, _ = apiRequest(t, srv, http.MethodDelete, empty, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodGet, empty, nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "project_not_found")
+}
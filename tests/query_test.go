@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listTasks(t *testing.T, srv *httptest.Server, query url.Values) (int, model.TaskList) {
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks?"+query.Encode(), nil)
+	var list model.TaskList
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.Unmarshal(body, &list))
+	}
+	return resp.StatusCode, list
+}
+
+func TestTaskQuery(t *testing.T) {
+	srv := newAPI(t)
+
+	for i := 1; i <= 7; i++ {
+		status := "todo"
+		if i%2 == 0 {
+			status = "done"
+		}
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{
+			"title":       fmt.Sprintf("Задача %d", i),
+			"description": fmt.Sprintf("report %d", i%3),
+			"date":        fmt.Sprintf("202401%02d", 10-i),
+			"status":      status,
+		})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+
+	_, list := listTasks(t, srv, url.Values{"status": {"done"}})
+	assert.Len(t, list.Tasks, 3)
+
+	_, list = listTasks(t, srv, url.Values{"date_from": {"20240104"}, "date_to": {"20240106"}, "status": {"todo,done"}})
+	assert.Len(t, list.Tasks, 3)
+
+	_, list = listTasks(t, srv, url.Values{"description": {"REPORT 1"}})
+	assert.Len(t, list.Tasks, 3)
+
+	// Постраничный обход по убыванию заголовка возвращает все задачи без повторов
+	var titles []string
+	query := url.Values{"sort": {"-title"}, "limit": {"3"}}
+	for page := 0; page < 5; page++ {
+		code, list := listTasks(t, srv, query)
+		require.Equal(t, http.StatusOK, code)
+		for _, task := range list.Tasks {
+			titles = append(titles, task.Title)
+		}
+		if list.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", list.NextCursor)
+	}
+	require.Len(t, titles, 7)
+	assert.Equal(t, "Задача 7", titles[0])
+	assert.Equal(t, "Задача 1", titles[6])
+
+	code, _ := listTasks(t, srv, url.Values{"sort": {"title"}, "cursor": {query.Get("cursor")}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code, "курсор выдан для другой сортировки")
+
+	code, _ = listTasks(t, srv, url.Values{"sort": {"title; DROP TABLE todolist"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	code, _ = listTasks(t, srv, url.Values{"limit": {"100000"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
+
+func TestTaskSearch(t *testing.T) {
+	srv := newAPI(t)
+
+	for _, task := range []map[string]any{
+		{"title": "Сходить в бассейн", "date": "20240110"},
+		{"title": "Поплавать", "description": "Бассейн с тренером <b>утром</b>", "date": "20240111"},
+		{"title": "Позвонить в УК", "description": "Разобраться с горячей водой", "date": "20240112"},
+		{"title": "Просмотр фильма", "description": "с попкорном", "date": "20240113"},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+
+	// Падеж не важен, совпадение в заголовке релевантнее совпадения в описании
+	_, list := listTasks(t, srv, url.Values{"q": {"бассейне"}})
+	require.Len(t, list.Tasks, 2)
+	assert.Equal(t, "Сходить в бассейн", list.Tasks[0].Title)
+	assert.Greater(t, list.Tasks[0].Rank, list.Tasks[1].Rank)
+	assert.Equal(t, "Сходить в <mark>бассейн</mark>", list.Tasks[0].Highlights.Title)
+	assert.Equal(t, "<mark>Бассейн</mark> с тренером &lt;b&gt;утром&lt;/b&gt;", list.Tasks[1].Highlights.Description)
+
+	_, list = listTasks(t, srv, url.Values{"q": {`"горячей водой"`}})
+	assert.Len(t, list.Tasks, 1)
+	_, list = listTasks(t, srv, url.Values{"q": {`"водой горячей"`}})
+	assert.Empty(t, list.Tasks)
+
+	_, list = listTasks(t, srv, url.Values{"q": {"попкор*"}})
+	assert.Len(t, list.Tasks, 1)
+
+	_, list = listTasks(t, srv, url.Values{"q": {"уК"}})
+	assert.Len(t, list.Tasks, 1)
+
+	// Поиск комбинируется с остальными фильтрами и постраничной выдачей
+	_, list = listTasks(t, srv, url.Values{"q": {"бассейн"}, "date_from": {"20240111"}})
+	assert.Len(t, list.Tasks, 1)
+
+	_, first := listTasks(t, srv, url.Values{"q": {"бассейн"}, "limit": {"1"}})
+	require.Len(t, first.Tasks, 1)
+	require.NotEmpty(t, first.NextCursor)
+	_, second := listTasks(t, srv, url.Values{"q": {"бассейн"}, "limit": {"1"}, "cursor": {first.NextCursor}})
+	require.Len(t, second.Tasks, 1)
+	assert.NotEqual(t, first.Tasks[0].Id, second.Tasks[0].Id)
+	assert.Empty(t, second.NextCursor)
+
+	_, list = listTasks(t, srv, url.Values{"q": {"12.01.2024"}})
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, "Позвонить в УК", list.Tasks[0].Title)
+
+	// Индекс обновляется вместе с задачей
+	resp, _ := apiRequest(t, srv, http.MethodPut, "/api/v1/tasks/"+itoa(list.Tasks[0].Id),
+		map[string]any{"title": "Написать в управляющую компанию", "date": "20240112"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, list = listTasks(t, srv, url.Values{"q": {"УК"}})
+	assert.Empty(t, list.Tasks)
+	_, list = listTasks(t, srv, url.Values{"q": {"управляющей компании"}})
+	assert.Len(t, list.Tasks, 1)
+}
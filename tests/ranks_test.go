@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskMove(t *testing.T) {
+	srv := newAPI(t)
+	inbox := "/api/v1/projects/" + itoa(model.InboxProjectId)
+
+	ids := map[string]int64{}
+	locations := map[string]string{}
+	for _, title := range []string{"Завтрак", "Зарядка", "Почта", "Созвон"} {
+		locations[title] = createSubtask(t, srv, title, 0)
+		ids[title] = getTask(t, srv, locations[title]).Id
+	}
+
+	resp, body := apiRequest(t, srv, http.MethodPost, locations["Созвон"]+"/move", map[string]any{"before": ids["Завтрак"]})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"Созвон", "Завтрак", "Зарядка", "Почта"}, projectTasks(t, srv, inbox))
+
+	resp, body = apiRequest(t, srv, http.MethodPost, locations["Завтрак"]+"/move", map[string]any{"after": ids["Почта"]})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"Созвон", "Зарядка", "Почта", "Завтрак"}, projectTasks(t, srv, inbox))
+
+	resp, body = apiRequest(t, srv, http.MethodPost, locations["Почта"]+"/move", map[string]any{"after": ids["Созвон"]})
+	require.Equal(t, http.StatusNoContent, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"Созвон", "Почта", "Зарядка", "Завтрак"}, projectTasks(t, srv, inbox))
+
+	// Новая задача становится последней в проекте
+	createSubtask(t, srv, "Обед", 0)
+	assert.Equal(t, []string{"Созвон", "Почта", "Зарядка", "Завтрак", "Обед"}, projectTasks(t, srv, inbox))
+
+	for _, move := range []map[string]any{{}, {"before": ids["Почта"], "after": ids["Зарядка"]}} {
+		resp, body = apiRequest(t, srv, http.MethodPost, locations["Созвон"]+"/move", move)
+		requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_move")
+	}
+	resp, body = apiRequest(t, srv, http.MethodPost, locations["Созвон"]+"/move", map[string]any{"before": ids["Созвон"]})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_move_target")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Работа"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "project_id": 2})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	report := getTask(t, srv, resp.Header.Get("Location")).Id
+	resp, body = apiRequest(t, srv, http.MethodPost, locations["Созвон"]+"/move", map[string]any{"after": report})
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_move_target")
+	assert.Equal(t, map[string]string{"after": "invalid_move_target"}, fieldCodes(p))
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks/999/move", map[string]any{"after": report})
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+}
+
+func TestRankRebalance(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+
+	var ids []int64
+	for _, title := range []string{"a", "b", "c"} {
+		id, err := store.InsertTask(ctx, model.Task{Title: title})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// Каждый перенос попадает в промежуток между первой задачей и предыдущей перенесенной,
+	// поэтому без перераспределения ранги росли бы на цифру каждые несколько переносов
+	order := append([]int64{}, ids...)
+	for i := 0; i < 200; i++ {
+		moved, err := store.MoveTask(ctx, order[2], order[1], false)
+		require.NoError(t, err)
+		require.True(t, moved)
+		order[1], order[2] = order[2], order[1]
+	}
+
+	tasks, _, err := store.FindTasks(ctx, model.TaskFilter{Sort: "position"})
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	for i, task := range tasks {
+		assert.Equal(t, order[i], task.Id)
+		assert.LessOrEqual(t, len(task.SortRank), settings.TaskRankMaxLength)
+	}
+
+	moved, err := store.MoveTask(ctx, ids[0], ids[0], true)
+	require.NoError(t, err)
+	assert.False(t, moved, "задачу нельзя перенести относительно нее самой")
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/auth"
+	"github.com/ZnNr/todo-list/internal/handlers"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/ratelimit"
+	"github.com/ZnNr/todo-list/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	for value, expected := range map[string]ratelimit.Limit{
+		"600/m":   {Requests: 600, Period: time.Minute},
+		" 10/s ":  {Requests: 10, Period: time.Second},
+		"1000/h":  {Requests: 1000, Period: time.Hour},
+		"100/30s": {Requests: 100, Period: 30 * time.Second},
+		"off":     {},
+		"0":       {},
+	} {
+		limit, err := ratelimit.ParseLimit(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, limit, value)
+	}
+	for _, value := range []string{"", "600", "-1/m", "ten/m", "10/d", "10/-1s"} {
+		_, err := ratelimit.ParseLimit(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRateLimitSettings(t *testing.T) {
+	t.Setenv("TODO_RATE_LIMIT_TASKS", "120/m")
+	t.Setenv("TODO_RATE_LIMIT_AUTH", "")
+	limits := settings.RateLimits()
+	assert.Equal(t, "120/m", limits["tasks"])
+	assert.Equal(t, "30/m", limits["auth"], "пустая переменная не отменяет значение по умолчанию")
+	assert.Equal(t, "600/m", limits[""])
+	assert.Equal(t, "1200/m", limits["ip"])
+}
+
+func TestRateLimitBucket(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 3, Period: time.Minute}
+	now := time.Now()
+
+	// Полная корзина позволяет сделать Requests запросов подряд, дальше - по запросу в 20 секунд
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := store.Take(context.Background(), "alice", limit, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+	result, err := store.Take(context.Background(), "alice", limit, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 3, result.Limit)
+	assert.Equal(t, 20*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	result, _ = store.Take(context.Background(), "bob", limit, now)
+	assert.True(t, result.Allowed, "у каждого клиента своя корзина")
+	result, _ = store.Take(context.Background(), "alice", limit, now.Add(20*time.Second))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Пополнившиеся корзины удаляются
+	require.Equal(t, 2, store.Len())
+	store.Take(context.Background(), "carol", limit, now.Add(2*time.Minute))
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestRateLimit(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "", time.Hour)
+	handlers.RateLimiterInstance = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		"":      {Requests: 100, Period: time.Minute},
+		"tasks": {Requests: 2, Period: time.Minute},
+	})
+	t.Cleanup(func() { handlers.RateLimiterInstance = nil })
+	alice := createUser(t, srv, "alice")
+	key := createAPIKey(t, srv, alice, "ci", model.ScopeTasksRead)
+
+	for _, remaining := range []string{"1", "0"} {
+		resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, remaining, resp.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", resp.Header.Get("RateLimit-Policy"))
+	}
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Лишняя"})
+	requireProblem(t, resp, body, http.StatusTooManyRequests, "rate_limited")
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header.Get("RateLimit-Reset"))
+
+	// Устаревшие маршруты расходуют те же запросы, что и ресурс задач
+	resp, body = apiRequest(t, srv, http.MethodGet, "/tasks", nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Contains(t, string(body), `"error"`)
+
+	// Пользователь и его ключ API ограничиваются отдельно от адреса и друг от друга, а другие группы - отдельно
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, "Authorization", "Bearer "+key.Key)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Remaining"))
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/projects", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "100", resp.Header.Get("RateLimit-Limit"))
+
+	// Без ограничения группы заголовков нет
+	handlers.RateLimiterInstance = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{})
+	resp, _ = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+}
+
+func TestRateLimitUnauthorized(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "qwerty", time.Hour)
+	handlers.RateLimiterInstance = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		"":   {Requests: 100, Period: time.Minute},
+		"ip": {Requests: 3, Period: time.Minute},
+	})
+	t.Cleanup(func() { handlers.RateLimiterInstance = nil })
+
+	// Запросы с неверным ключом или токеном ограничиваются по адресу до проверки, иначе их можно подбирать без ограничений
+	for _, headers := range [][]string{
+		{"Authorization", "Bearer " + auth.APIKeyPrefix + "guess"},
+		{"Authorization", "Bearer guess"},
+		{"Cookie", "token=guess"},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tasks", nil, headers...)
+		requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+	}
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/projects", nil, "Authorization", "Bearer guess")
+	requireProblem(t, resp, body, http.StatusTooManyRequests, "rate_limited")
+	assert.Equal(t, "3", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "20", resp.Header.Get("Retry-After"))
+	resp, body = apiRequest(t, srv, http.MethodGet, "/tasks", nil, "Authorization", "Bearer "+auth.APIKeyPrefix+"guess")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, string(body))
+
+	// Вход и регистрация ограничиваются своей группой
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/signin", map[string]any{"password": "qwerty"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+}
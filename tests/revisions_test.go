@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevertTask(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{
+		"title":       "Отчет",
+		"description": "Подробное описание, которое жалко потерять",
+		"date":        "20240301",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"description": "упс"},
+		"Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodPatch, location, map[string]any{"status": "in_progress"},
+		"Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/revisions", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list struct {
+		Revisions []model.TaskRevision `json:"revisions"`
+	}
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Revisions, 3)
+	assert.EqualValues(t, 3, list.Revisions[0].Revision)
+	assert.Equal(t, "update", list.Revisions[0].Action)
+	assert.Equal(t, "упс", list.Revisions[1].Task.Description)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/revisions/1", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var first model.TaskRevision
+	require.NoError(t, json.Unmarshal(body, &first))
+	assert.Equal(t, "Подробное описание, которое жалко потерять", first.Task.Description)
+
+	// Предпросмотр показывает изменения, но не изменяет задачу
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/revisions/1/diff", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var preview model.RevertPreview
+	require.NoError(t, json.Unmarshal(body, &preview))
+	assert.EqualValues(t, 1, preview.Revision)
+	assert.EqualValues(t, 3, preview.Version)
+	assert.Equal(t, model.FieldChange{From: "упс", To: "Подробное описание, которое жалко потерять"}, preview.Changes["description"])
+	assert.Equal(t, model.FieldChange{From: "in_progress", To: "todo"}, preview.Changes["status"])
+	assert.NotContains(t, preview.Changes, "title")
+	assert.EqualValues(t, 3, getTask(t, srv, location).Version)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/revert?revision=1", nil, "If-Match", `"2"`)
+	requireProblem(t, resp, body, http.StatusPreconditionFailed, "precondition_failed")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/revert?revision=1", nil, "If-Match", `"3"`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+	task := getTask(t, srv, location)
+	assert.Equal(t, "Подробное описание, которое жалко потерять", task.Description)
+	assert.Equal(t, model.StatusTodo, task.Status)
+
+	// Возврат сохраняется новым снимком и его тоже можно отменить
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/revert?revision=3", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	task = getTask(t, srv, location)
+	assert.Equal(t, "упс", task.Description)
+	assert.Equal(t, model.StatusInProgress, task.Status)
+	assert.EqualValues(t, 5, task.Version)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/history?action=revert", nil)
+	assert.Len(t, eventList(t, resp, body).Events, 2)
+
+	// Выполненная задача возвращается в работу только через reopen, в том числе при возврате к снимку
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/revisions/1/diff", nil)
+	requireProblem(t, resp, body, http.StatusConflict, "illegal_transition")
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/revert?revision=1", nil)
+	requireProblem(t, resp, body, http.StatusConflict, "illegal_transition")
+	task = getTask(t, srv, location)
+	assert.Equal(t, model.StatusDone, task.Status)
+	assert.NotNil(t, task.CompletedAt)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/revert?revision=6", nil)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_revision")
+	resp, body = apiRequest(t, srv, http.MethodPost, location+"/revert?revision=abc", nil)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_revision")
+	resp, body = apiRequest(t, srv, http.MethodGet, location+"/revisions/9", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "revision_not_found")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks/999/revert?revision=1", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+}
+
+func TestRevertSubtasks(t *testing.T) {
+	srv := newAPI(t)
+
+	parent := createSubtask(t, srv, "Переезд", 0)
+	resp, _ := apiRequest(t, srv, http.MethodPost, parent+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	done := getTask(t, srv, parent).Version
+	resp, _ = apiRequest(t, srv, http.MethodPost, parent+"/reopen", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	boxes := createSubtask(t, srv, "Собрать коробки", getTask(t, srv, parent).Id)
+
+	// Возврат к выполненному снимку завершает и подзадачи, как отметка о выполнении
+	resp, body := apiRequest(t, srv, http.MethodPost, parent+"/revert?revision="+itoa(done), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, model.StatusDone, getTask(t, srv, parent).Status)
+	subtask := getTask(t, srv, boxes)
+	assert.Equal(t, model.StatusDone, subtask.Status)
+	assert.NotNil(t, subtask.CompletedAt)
+}
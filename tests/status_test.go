@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTask(t *testing.T, srv *httptest.Server, location string) model.Task {
+	resp, body := apiRequest(t, srv, http.MethodGet, location, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var task model.Task
+	require.NoError(t, json.Unmarshal(body, &task))
+	return task
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, _ := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "date": "20240129"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	location := resp.Header.Get("Location")
+
+	task := getTask(t, srv, location)
+	assert.Equal(t, model.StatusTodo, task.Status)
+	require.NotNil(t, task.CreatedAt)
+	require.NotNil(t, task.UpdatedAt)
+	assert.Nil(t, task.CompletedAt)
+
+	for _, status := range []string{model.StatusInProgress, model.StatusBlocked} {
+		resp, _ = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет", "date": "20240129", "status": status})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// Заблокированную задачу нельзя выполнить
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/done", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет", "date": "20240129", "status": "in_progress"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	done := getTask(t, srv, location)
+	assert.Equal(t, model.StatusDone, done.Status)
+	require.NotNil(t, done.CompletedAt)
+	assert.Equal(t, task.CreatedAt, done.CreatedAt)
+	assert.True(t, done.UpdatedAt.After(*task.UpdatedAt))
+
+	// Выполненная задача возвращается в работу только через reopen
+	resp, _ = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет", "date": "20240129", "status": "todo"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/reopen", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	reopened := getTask(t, srv, location)
+	assert.Equal(t, model.StatusTodo, reopened.Status)
+	assert.Nil(t, reopened.CompletedAt)
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, location+"/reopen", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = apiRequest(t, srv, http.MethodPut, location, map[string]any{"title": "Отчет", "status": "paused"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
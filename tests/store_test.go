@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openStore(t *testing.T) *database.TaskData {
+	store, err := database.NewTaskData("sqlite://" + filepath.Join(t.TempDir(), "todolist.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.CloseDb() })
+	require.NoError(t, store.Migrate(context.Background()))
+	return store
+}
+
+func TestTaskStore(t *testing.T) {
+	ctx := context.Background()
+	var store database.TaskStore = openStore(t)
+
+	id, err := store.InsertTask(ctx, model.Task{Date: "20240129", Title: "Todo", Description: "Описание", Status: "Не выполнено"})
+	require.NoError(t, err)
+
+	task, err := store.GetTask(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Todo", task.Title)
+	assert.Equal(t, "Описание", task.Description)
+	assert.Equal(t, "Не выполнено", task.Status)
+
+	assert.EqualValues(t, 1, task.Version)
+
+	task.Status = "Выполнено"
+	updated, err := store.UpdateTask(ctx, task, model.ActionUpdate)
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	// Повторное обновление прочитанной ранее версии не применяется
+	updated, err = store.UpdateTask(ctx, task, model.ActionUpdate)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	list, _, err := store.FindTasks(ctx, model.TaskFilter{Statuses: []string{"Выполнено"}, DateFrom: "20240129", DateTo: "20240129", Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	deleted, err := store.DeleteTask(ctx, id, 1)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	deleted, err = store.DeleteTask(ctx, id, 2)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	list, _, err = store.FindTasks(ctx, model.TaskFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
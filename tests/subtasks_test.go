@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createSubtask создает задачу с родительской задачей parent (0 - задачу верхнего уровня) и возвращает ее адрес
+func createSubtask(t *testing.T, srv *httptest.Server, title string, parent int64) string {
+	t.Helper()
+	task := map[string]any{"title": title}
+	if parent > 0 {
+		task["parent_id"] = parent
+	}
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	return resp.Header.Get("Location")
+}
+
+// taskTree возвращает дерево задачи
+func taskTree(t *testing.T, srv *httptest.Server, location string) model.TaskTree {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodGet, location+"/tree", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var tree model.TaskTree
+	require.NoError(t, json.Unmarshal(body, &tree))
+	return tree
+}
+
+func TestSubtaskTree(t *testing.T) {
+	srv := newAPI(t)
+
+	move := createSubtask(t, srv, "Переезд", 0)
+	moveID := getTask(t, srv, move).Id
+	pack := createSubtask(t, srv, "Упаковать вещи", moveID)
+	packID := getTask(t, srv, pack).Id
+	createSubtask(t, srv, "Заказать машину", moveID)
+	boxes := createSubtask(t, srv, "Купить коробки", packID)
+
+	tree := taskTree(t, srv, move)
+	assert.Equal(t, "Переезд", tree.Title)
+	require.Len(t, tree.Subtasks, 2)
+	assert.Equal(t, "Упаковать вещи", tree.Subtasks[0].Title)
+	assert.Equal(t, "Заказать машину", tree.Subtasks[1].Title)
+	require.Len(t, tree.Subtasks[0].Subtasks, 1)
+	assert.Equal(t, "Купить коробки", tree.Subtasks[0].Subtasks[0].Title)
+	assert.Empty(t, tree.Subtasks[1].Subtasks)
+
+	resp, _ := apiRequest(t, srv, http.MethodPost, boxes+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, &model.TaskProgress{Done: 1, Total: 3, Percent: 33}, getTask(t, srv, move).Progress,
+		"выполнение учитывает подзадачи любой вложенности")
+	assert.Equal(t, &model.TaskProgress{Done: 1, Total: 1, Percent: 100}, getTask(t, srv, pack).Progress)
+	assert.Nil(t, getTask(t, srv, boxes).Progress)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Сдать ключи", "parent_id": 99})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_parent")
+
+	for _, parent := range []int64{moveID, getTask(t, srv, boxes).Id} {
+		resp, body = apiRequest(t, srv, http.MethodPatch, move, map[string]any{"parent_id": parent}, "Content-Type", patch.MergePatchType)
+		requireProblem(t, resp, body, http.StatusUnprocessableEntity, "parent_cycle")
+	}
+
+	// Без parent_id PUT оставляет родителя, null в PATCH делает задачу задачей верхнего уровня
+	resp, body = apiRequest(t, srv, http.MethodPut, pack, map[string]any{"title": "Упаковать книги"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, &moveID, getTask(t, srv, pack).ParentId)
+	resp, body = apiRequest(t, srv, http.MethodPatch, pack, map[string]any{"parent_id": nil}, "Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Nil(t, getTask(t, srv, pack).ParentId)
+	assert.Len(t, taskTree(t, srv, move).Subtasks, 1)
+	assert.Len(t, taskTree(t, srv, pack).Subtasks, 1)
+}
+
+func TestSubtaskCascade(t *testing.T) {
+	srv := newAPI(t)
+
+	parent := createSubtask(t, srv, "Отпуск", 0)
+	parentID := getTask(t, srv, parent).Id
+	tickets := createSubtask(t, srv, "Купить билеты", parentID)
+	hotel := createSubtask(t, srv, "Забронировать отель", parentID)
+	visa := createSubtask(t, srv, "Оформить визу", getTask(t, srv, hotel).Id)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Путешествия"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodPatch, parent, map[string]any{"project_id": 2}, "Content-Type", patch.MergePatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, int64(2), getTask(t, srv, visa).ProjectId, "подзадачи переносятся в проект родителя")
+	resp, body = apiRequest(t, srv, http.MethodPatch, tickets, map[string]any{"project_id": 1}, "Content-Type", patch.MergePatchType)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "subtask_project")
+
+	// Удаление переносит в корзину все дерево, а вернуть подзадачу без родителя нельзя
+	resp, _ = apiRequest(t, srv, http.MethodDelete, parent, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Len(t, trashList(t, srv).Tasks, 4)
+	resp, body = apiRequest(t, srv, http.MethodPost, visa+"/restore", nil)
+	requireProblem(t, resp, body, http.StatusConflict, "parent_in_trash")
+	resp, _ = apiRequest(t, srv, http.MethodPost, parent+"/restore", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, trashList(t, srv).Tasks)
+	assert.Len(t, taskTree(t, srv, parent).Subtasks, 2)
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, tickets+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodPost, parent+"/done", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	for _, location := range []string{tickets, hotel, visa} {
+		assert.Equal(t, model.StatusDone, getTask(t, srv, location).Status)
+	}
+	resp, body = apiRequest(t, srv, http.MethodGet, visa+"/history?action=done", nil)
+	assert.Len(t, eventList(t, resp, body).Events, 1)
+	resp, body = apiRequest(t, srv, http.MethodGet, tickets+"/history?action=done", nil)
+	assert.Len(t, eventList(t, resp, body).Events, 1, "выполненная подзадача не завершается повторно")
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, parent, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash/"+itoa(parentID), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, trashList(t, srv).Tasks)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/audit?action=purge", nil)
+	assert.Len(t, eventList(t, resp, body).Events, 4, "каждая подзадача попадает в журнал")
+}
+
+func TestSubtaskProgressETag(t *testing.T) {
+	srv := newAPI(t)
+	renovation := createSubtask(t, srv, "Ремонт", 0)
+	kitchen := createSubtask(t, srv, "Кухня", getTask(t, srv, renovation).Id)
+	resp, _ := apiRequest(t, srv, http.MethodGet, renovation, nil)
+	tag := resp.Header.Get("ETag")
+
+	// Изменения подзадач любой вложенности, которые меняют progress, меняют и ETag родительских задач
+	paint := createSubtask(t, srv, "Покрасить стены", getTask(t, srv, kitchen).Id)
+	tag = requireETagChanged(t, srv, renovation, tag)
+	for _, change := range []struct{ method, path string }{
+		{http.MethodPost, paint + "/done"},
+		{http.MethodPost, paint + "/reopen"},
+		{http.MethodDelete, paint},
+		{http.MethodPost, paint + "/restore"},
+	} {
+		resp, body := apiRequest(t, srv, change.method, change.path, nil)
+		require.Less(t, resp.StatusCode, 300, string(body))
+		tag = requireETagChanged(t, srv, renovation, tag)
+	}
+	resp, body := apiRequest(t, srv, http.MethodPatch, paint, map[string]any{"parent_id": 0}, "Content-Type", "application/merge-patch+json")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	tag = requireETagChanged(t, srv, renovation, tag)
+
+	// Изменения, которые не меняют progress, ETag родительской задачи не меняют
+	resp, body = apiRequest(t, srv, http.MethodPatch, kitchen, map[string]any{"title": "Кухня и коридор"},
+		"Content-Type", "application/merge-patch+json")
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	resp, _ = apiRequest(t, srv, http.MethodGet, renovation, nil, "If-None-Match", tag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/ZnNr/todo-list/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskTags(t *testing.T) {
+	srv := newAPI(t)
+
+	create := func(title string, tags ...string) string {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": title, "tags": tags})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		return resp.Header.Get("Location")
+	}
+	milk := create("Купить молоко", "Дом", "покупки", "дом")
+	report := create("Отчет", "работа")
+	call := create("Позвонить в банк", "работа", "дом")
+
+	assert.Equal(t, []string{"дом", "покупки"}, getTask(t, srv, milk).Tags, "теги приводятся к нижнему регистру без повторов")
+
+	code, list := listTasks(t, srv, url.Values{"tag": {"дом,работа"}, "sort": {"title"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Купить молоко", "Отчет", "Позвонить в банк"}, titles(list.Tasks))
+
+	code, list = listTasks(t, srv, url.Values{"tag": {"дом", "Работа"}, "tag_match": {"all"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Позвонить в банк"}, titles(list.Tasks))
+
+	// PUT без тегов оставляет теги, пустой список снимает все теги
+	resp, body := apiRequest(t, srv, http.MethodPut, report, map[string]any{"title": "Отчет за март"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"работа"}, getTask(t, srv, report).Tags)
+	resp, body = apiRequest(t, srv, http.MethodPut, report, map[string]any{"title": "Отчет за март", "tags": []string{}})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Empty(t, getTask(t, srv, report).Tags)
+
+	resp, body = apiRequest(t, srv, http.MethodPatch, call, []map[string]any{
+		{"op": "add", "path": "/tags/-", "value": "срочно"},
+	}, "Content-Type", patch.JSONPatchType)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, []string{"дом", "работа", "срочно"}, getTask(t, srv, call).Tags)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, call+"/history?action=update", nil)
+	history := eventList(t, resp, body)
+	require.Len(t, history.Events, 1)
+	assert.Equal(t, []any{"дом", "работа", "срочно"}, history.Events[0].Changes["tags"].To)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "tags": []string{"дом", "a,b"}})
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_tag")
+	assert.Equal(t, map[string]string{"tags": "invalid_tag"}, fieldCodes(p))
+}
+
+func TestTagCRUD(t *testing.T) {
+	srv := newAPI(t)
+
+	for _, task := range []map[string]any{
+		{"title": "Купить молоко", "tags": []string{"дом", "покупки"}},
+		{"title": "Позвонить в банк", "tags": []string{"дом"}},
+		{"title": "Отчет", "tags": []string{"работа"}},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	}
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tags", map[string]any{"name": "Дача"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	location := resp.Header.Get("Location")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tags", map[string]any{"name": "дача"})
+	requireProblem(t, resp, body, http.StatusConflict, "tag_exists")
+
+	tags := func(query string) []model.Tag {
+		resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tags"+query, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		var list model.TagList
+		require.NoError(t, json.Unmarshal(body, &list))
+		return list.Tags
+	}
+	all := tags("")
+	require.Len(t, all, 4)
+	assert.Equal(t, model.Tag{Id: all[0].Id, Name: "дом", Count: 2}, all[0], "часто используемые теги идут первыми")
+	assert.Equal(t, "дача", all[3].Name)
+	assert.Zero(t, all[3].Count)
+
+	prefixed := tags("?prefix=" + url.QueryEscape("Д"))
+	assert.Len(t, prefixed, 2)
+
+	resp, body = apiRequest(t, srv, http.MethodPut, location, map[string]any{"name": "дом"})
+	requireProblem(t, resp, body, http.StatusConflict, "tag_exists")
+
+	home := "/api/v1/tags/" + itoa(all[0].Id)
+	resp, body = apiRequest(t, srv, http.MethodPut, home, map[string]any{"name": "Семья"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	code, list := listTasks(t, srv, url.Values{"tag": {"семья"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Tasks, 2, "переименованный тег остается у задач")
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, home, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodGet, home, nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "tag_not_found")
+	code, list = listTasks(t, srv, url.Values{"tag": {"семья"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, list.Tasks)
+}
+
+func TestTagETag(t *testing.T) {
+	srv := newAPI(t)
+	var locations []string
+	for _, task := range []map[string]any{
+		{"title": "Купить молоко", "tags": []string{"дом"}},
+		{"title": "Отчет", "tags": []string{"работа"}},
+	} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		locations = append(locations, resp.Header.Get("Location"))
+	}
+	milk, report := locations[0], locations[1]
+	resp, _ := apiRequest(t, srv, http.MethodGet, milk, nil)
+	milkTag := resp.Header.Get("ETag")
+	resp, _ = apiRequest(t, srv, http.MethodGet, report, nil)
+	reportTag := resp.Header.Get("ETag")
+
+	// Переименование и удаление тега меняют tags, а значит и ETag, только у задач с этим тегом
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/tags?prefix="+url.QueryEscape("дом"), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list model.TagList
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Tags, 1)
+	home := "/api/v1/tags/" + itoa(list.Tags[0].Id)
+
+	resp, body = apiRequest(t, srv, http.MethodPut, home, map[string]any{"name": "семья"})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	milkTag = requireETagChanged(t, srv, milk, milkTag)
+	resp, _ = apiRequest(t, srv, http.MethodGet, report, nil, "If-None-Match", reportTag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, home, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	requireETagChanged(t, srv, milk, milkTag)
+	assert.Empty(t, getTask(t, srv, milk).Tags)
+}
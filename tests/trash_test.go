@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/database"
+	"github.com/ZnNr/todo-list/internal/model"
+	taskservice "github.com/ZnNr/todo-list/internal/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trashList возвращает задачи из корзины
+func trashList(t *testing.T, srv *httptest.Server) model.TaskList {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodGet, "/api/v1/trash", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var list model.TaskList
+	require.NoError(t, json.Unmarshal(body, &list))
+	return list
+}
+
+func TestTrash(t *testing.T) {
+	srv := newAPI(t)
+
+	var locations []string
+	for _, title := range []string{"Купить молоко", "Позвонить маме", "Оплатить счет"} {
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": title, "date": "20240301"})
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		locations = append(locations, resp.Header.Get("Location"))
+	}
+
+	for _, location := range locations[:2] {
+		resp, _ := apiRequest(t, srv, http.MethodDelete, location, nil)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+
+	resp, body := apiRequest(t, srv, http.MethodGet, locations[0], nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+	resp, body = apiRequest(t, srv, http.MethodPut, locations[0], map[string]any{"title": "Купить кефир"})
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_found")
+
+	code, list := listTasks(t, srv, url.Values{})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, "Оплатить счет", list.Tasks[0].Title)
+
+	code, list = listTasks(t, srv, url.Values{"q": {"молоко"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, list.Tasks, "задачи из корзины не участвуют в поиске")
+
+	trash := trashList(t, srv)
+	require.Len(t, trash.Tasks, 2)
+	assert.NotNil(t, trash.Tasks[0].DeletedAt)
+
+	resp, _ = apiRequest(t, srv, http.MethodPost, locations[0]+"/restore", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	restored := getTask(t, srv, locations[0])
+	assert.Equal(t, "Купить молоко", restored.Title)
+	assert.Nil(t, restored.DeletedAt)
+
+	resp, body = apiRequest(t, srv, http.MethodPost, locations[0]+"/restore", nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_in_trash")
+
+	// Окончательно удалить можно только задачу из корзины
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash/"+itoa(restored.Id), nil)
+	requireProblem(t, resp, body, http.StatusNotFound, "task_not_in_trash")
+
+	trashed := trashList(t, srv).Tasks[0]
+	resp, _ = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash/"+itoa(trashed.Id), nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, trashList(t, srv).Tasks)
+
+	resp, _ = apiRequest(t, srv, http.MethodDelete, locations[2], nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"purged": 1}`, string(body))
+}
+
+func TestTrashRetention(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+	service := taskservice.InitTaskService(store)
+
+	id, err := store.InsertTask(ctx, model.Task{Date: "20240301", Title: "Старая задача", Status: model.StatusTodo})
+	require.NoError(t, err)
+	deleted, err := store.DeleteTask(ctx, id, 1)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	// Задача моложе срока хранения остается в корзине
+	purged, err := store.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	runCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	service.RunTrashRetention(runCtx, time.Nanosecond, 10*time.Millisecond)
+
+	list, err := service.ListTrash(ctx, model.TaskFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Tasks)
+}
+
+func TestTrashRetentionRemovedMember(t *testing.T) {
+	store := openStore(t)
+	service := taskservice.InitTaskService(store)
+	alice, err := store.InsertUser(context.Background(), model.User{Login: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+	bob, err := store.InsertUser(context.Background(), model.User{Login: "bob", PasswordHash: "hash"})
+	require.NoError(t, err)
+	aliceCtx, bobCtx := database.WithOwner(context.Background(), alice), database.WithOwner(context.Background(), bob)
+	project, err := store.InsertProject(aliceCtx, model.Project{Name: "Ремонт"})
+	require.NoError(t, err)
+	invitation, err := store.InsertInvitation(aliceCtx, model.Invitation{ProjectId: project, UserId: bob, Role: model.RoleEditor})
+	require.NoError(t, err)
+	_, err = store.AcceptInvitation(bobCtx, invitation)
+	require.NoError(t, err)
+
+	id, err := store.InsertTask(bobCtx, model.Task{Date: "20240301", Title: "Купить краску", Status: model.StatusTodo, ProjectId: project})
+	require.NoError(t, err)
+	deleted, err := store.DeleteTask(bobCtx, id, 1)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	removed, err := store.DeleteMember(aliceCtx, project, bob)
+	require.NoError(t, err)
+	require.True(t, removed)
+
+	// Исключенный участник не может очистить корзину от своей задачи, а фоновая очистка удаляет ее
+	purged, err := store.PurgeTrash(bobCtx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = store.PurgeTrash(database.WithAllOwners(context.Background()), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	list, err := service.ListTrash(aliceCtx, model.TaskFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Tasks)
+
+	events, _, err := store.FindEvents(bobCtx, model.EventFilter{TaskId: id, Actions: []string{model.ActionPurge}})
+	require.NoError(t, err)
+	assert.Len(t, events, 1, "запись об удалении попадает в журнал владельца задачи")
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/todo-list/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loginUser входит по логину и паролю и возвращает значение заголовка Cookie с токеном пользователя
+func loginUser(t *testing.T, srv *httptest.Server, login, password string) string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/users/login", map[string]any{"login": login, "password": password})
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var result struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(body, &result))
+	return "token=" + result.Token
+}
+
+// createUser регистрирует пользователя и возвращает значение заголовка Cookie с его токеном
+func createUser(t *testing.T, srv *httptest.Server, login string) string {
+	t.Helper()
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/users", map[string]any{"login": login, "password": login + "-password"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	return loginUser(t, srv, login, login+"-password")
+}
+
+func TestUsers(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "", time.Hour)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/users",
+		map[string]any{"login": " Alice ", "password": "correct horse", "name": "Алиса"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	assert.Equal(t, "/api/v1/users/me", resp.Header.Get("Location"))
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/users", map[string]any{"login": "alice", "password": "another one"})
+	requireProblem(t, resp, body, http.StatusConflict, "login_taken")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/users", map[string]any{"login": "a!", "password": "short"})
+	problem := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "validation_failed")
+	assert.Equal(t, map[string]string{"login": "invalid_login", "password": "weak_password"}, fieldCodes(problem))
+
+	// Неизвестный логин, неверный пароль и пользователь по умолчанию без пароля неразличимы
+	for _, credentials := range []map[string]any{
+		{"login": "alice", "password": "wrong horse"},
+		{"login": "bob", "password": "correct horse"},
+		{"login": "default", "password": ""},
+	} {
+		resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/users/login", credentials)
+		requireProblem(t, resp, body, http.StatusUnauthorized, "invalid_credentials")
+	}
+
+	alice := loginUser(t, srv, "ALICE", "correct horse")
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var profile model.User
+	require.NoError(t, json.Unmarshal(body, &profile))
+	assert.Equal(t, "alice", profile.Login)
+	assert.Equal(t, "Алиса", profile.Name)
+	assert.NotContains(t, string(body), "$2a$", "хеш пароля не возвращается")
+
+	// Без токена запросы выполняются от имени пользователя по умолчанию
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	require.NoError(t, json.Unmarshal(body, &profile))
+	assert.Equal(t, int64(model.DefaultUserId), profile.Id)
+
+	// Для смены пароля нужен текущий пароль, после смены прежние токены недействительны
+	resp, body = apiRequest(t, srv, http.MethodPut, "/api/v1/users/me",
+		map[string]any{"name": "Алиса", "password": "battery staple", "current_password": "wrong horse"}, "Cookie", alice)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "wrong_password")
+	resp, body = apiRequest(t, srv, http.MethodPut, "/api/v1/users/me",
+		map[string]any{"name": "Алиса Б.", "password": "battery staple", "current_password": "correct horse"}, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	require.NoError(t, json.Unmarshal(body, &profile))
+	assert.Equal(t, "Алиса Б.", profile.Name)
+
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me", nil, "Cookie", alice)
+	requireProblem(t, resp, body, http.StatusUnauthorized, "unauthorized")
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/users/login", map[string]any{"login": "alice", "password": "correct horse"})
+	requireProblem(t, resp, body, http.StatusUnauthorized, "invalid_credentials")
+	alice = loginUser(t, srv, "alice", "battery staple")
+	resp, _ = apiRequest(t, srv, http.MethodGet, "/api/v1/users/me", nil, "Cookie", alice)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestUserIsolation(t *testing.T) {
+	srv := newAPI(t)
+	enableAuth(t, "", time.Hour)
+	alice, bob := createUser(t, srv, "alice"), createUser(t, srv, "bob")
+
+	create := func(cookie string, task map[string]any) (string, int64) {
+		t.Helper()
+		resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", task, "Cookie", cookie)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+		var created struct {
+			Id int64 `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(body, &created))
+		return resp.Header.Get("Location"), created.Id
+	}
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Работа"}, "Cookie", alice)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	project := resp.Header.Get("Location")
+	report, reportID := create(alice, map[string]any{"title": "Отчет", "date": "20240301", "tags": []string{"работа"}})
+	_, draftID := create(alice, map[string]any{"title": "Черновик", "date": "20240301", "parent_id": reportID})
+	resp, _ = apiRequest(t, srv, http.MethodPut, report+"/dependencies/"+itoa(draftID), nil, "Cookie", alice)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	trashed, trashedID := create(alice, map[string]any{"title": "Старая задача", "date": "20240301"})
+	resp, _ = apiRequest(t, srv, http.MethodDelete, trashed, nil, "Cookie", alice)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Задачи Алисы для Боба не существуют, по какому бы маршруту он к ним ни обращался
+	_, bobTaskID := create(bob, map[string]any{"title": "Задача Боба", "date": "20240301"})
+	for _, request := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodGet, report, nil},
+		{http.MethodPut, report, map[string]any{"title": "Взлом", "date": "20240301"}},
+		{http.MethodPost, report + "/done", nil},
+		{http.MethodPost, report + "/reopen", nil},
+		{http.MethodGet, report + "/tree", nil},
+		{http.MethodGet, report + "/history", nil},
+		{http.MethodGet, report + "/revisions", nil},
+		{http.MethodGet, report + "/revisions/1", nil},
+		{http.MethodPost, report + "/revert?revision=1", nil},
+		{http.MethodGet, report + "/dependencies", nil},
+		{http.MethodDelete, report + "/dependencies/" + itoa(draftID), nil},
+		{http.MethodPut, "/api/v1/tasks/" + itoa(bobTaskID) + "/dependencies/" + itoa(reportID), nil},
+		{http.MethodPost, report + "/move", map[string]any{"before": bobTaskID}},
+		{http.MethodDelete, report, nil},
+		{http.MethodPost, trashed + "/restore", nil},
+		{http.MethodDelete, "/api/v1/trash/" + itoa(trashedID), nil},
+	} {
+		resp, body := apiRequest(t, srv, request.method, request.path, request.body, "Cookie", bob)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "%s %s: %s", request.method, request.path, body)
+	}
+	resp, body = apiRequest(t, srv, http.MethodPatch, report, map[string]any{"title": "Взлом"},
+		"Cookie", bob, "Content-Type", "application/merge-patch+json")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodGet, "/task?id="+itoa(reportID), nil, "Cookie", bob)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, string(body))
+
+	// Чужие задачи и проекты нельзя указать родителем или проектом своей задачи
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks",
+		map[string]any{"title": "Подзадача", "date": "20240301", "parent_id": reportID}, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_parent")
+	resp, body = apiRequest(t, srv, http.MethodGet, project, nil, "Cookie", bob)
+	requireProblem(t, resp, body, http.StatusNotFound, "project_not_found")
+
+	// Списки, теги, проекты, корзина и журнал Боба содержат только его данные
+	for path, unexpected := range map[string]string{
+		"/api/v1/tasks":    "Отчет",
+		"/api/v1/tags":     "работа",
+		"/api/v1/projects": "Работа",
+		"/api/v1/trash":    "Старая задача",
+		"/api/v1/audit":    "Отчет",
+	} {
+		resp, body := apiRequest(t, srv, http.MethodGet, path, nil, "Cookie", bob)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		assert.NotContains(t, string(body), unexpected, path)
+	}
+	// Имена проектов и тегов уникальны только в пределах пользователя
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/projects", map[string]any{"name": "Работа"}, "Cookie", bob)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tags", map[string]any{"name": "работа"}, "Cookie", bob)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+
+	// Очистка корзины Бобом не затрагивает корзину Алисы, а пользователь по умолчанию не видит ничьих задач
+	resp, _ = apiRequest(t, srv, http.MethodDelete, "/api/v1/trash", nil, "Cookie", bob)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/trash", nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Старая задача")
+	assert.Empty(t, taskView(t, srv, "/api/v1/tasks"))
+
+	// Задачи Алисы не изменились
+	resp, body = apiRequest(t, srv, http.MethodGet, report, nil, "Cookie", alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	var task model.Task
+	require.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "Отчет", task.Title)
+	assert.Equal(t, []string{"работа"}, task.Tags)
+	assert.Equal(t, []int64{draftID}, task.BlockedBy)
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ZnNr/todo-list/internal/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rawRequest отправляет тело запроса как есть с указанным типом содержимого
+func rawRequest(t *testing.T, url, contentType, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+// fieldCodes возвращает коды ошибок по полям
+func fieldCodes(p problem) map[string]string {
+	codes := map[string]string{}
+	for _, e := range p.Errors {
+		codes[e.Field] = e.Code
+	}
+	return codes
+}
+
+func TestRequestDecoding(t *testing.T) {
+	srv := newAPI(t)
+	tasksURL := srv.URL + "/api/v1/tasks"
+
+	resp, body := rawRequest(t, tasksURL, "text/plain", `{"title": "Отчет"}`)
+	requireProblem(t, resp, body, http.StatusUnsupportedMediaType, "unsupported_media_type")
+
+	resp, body = rawRequest(t, tasksURL, "", `{"title": "Отчет"}`)
+	requireProblem(t, resp, body, http.StatusUnsupportedMediaType, "unsupported_media_type")
+
+	resp, body = rawRequest(t, tasksURL, "application/json; charset=UTF-8", `{"title": "Отчет"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+
+	resp, body = rawRequest(t, tasksURL, "application/json", `{"title": "Отчет", "comment": "в 18:00"}`)
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "unknown_field")
+	assert.Equal(t, map[string]string{"comment": "unknown_field"}, fieldCodes(p))
+
+	resp, body = rawRequest(t, tasksURL, "application/json", `{"title": 5}`)
+	p = requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_type")
+	assert.Equal(t, map[string]string{"title": "invalid_type"}, fieldCodes(p))
+
+	resp, body = rawRequest(t, tasksURL, "application/json", `{"title": "Отчет"} {"title": "Еще"}`)
+	requireProblem(t, resp, body, http.StatusBadRequest, "malformed_body")
+
+	large := `{"title": "Отчет", "description": "` + strings.Repeat("a", int(settings.RequestBodyLimit)) + `"}`
+	resp, body = rawRequest(t, tasksURL, "application/json", large)
+	requireProblem(t, resp, body, http.StatusRequestEntityTooLarge, "body_too_large")
+}
+
+func TestTaskValidation(t *testing.T) {
+	srv := newAPI(t)
+
+	resp, body := apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{
+		"title":  strings.Repeat("я", settings.TaskTitleMaxLength+1),
+		"date":   "20241350",
+		"status": "paused",
+		"repeat": "d 0",
+	})
+	p := requireProblem(t, resp, body, http.StatusUnprocessableEntity, "validation_failed")
+	assert.Equal(t, map[string]string{
+		"title":  "too_long",
+		"date":   "invalid_date",
+		"status": "invalid_status",
+		"repeat": "invalid_repeat",
+	}, fieldCodes(p))
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "   "})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "title_required")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{"title": "Отчет", "date": "18991231"})
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "date_out_of_range")
+
+	resp, body = apiRequest(t, srv, http.MethodPost, "/api/v1/tasks", map[string]any{
+		"title":       strings.Repeat("я", settings.TaskTitleMaxLength),
+		"description": strings.Repeat("я", settings.TaskDescriptionMaxLength),
+	})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+
+	query := url.Values{"date_from": {"20240201"}, "date_to": {"20240101"}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks?"+query.Encode(), nil)
+	requireProblem(t, resp, body, http.StatusUnprocessableEntity, "invalid_date_range")
+
+	query = url.Values{"status": {"paused"}, "sort": {"color"}, "limit": {"1000"}}
+	resp, body = apiRequest(t, srv, http.MethodGet, "/api/v1/tasks?"+query.Encode(), nil)
+	p = requireProblem(t, resp, body, http.StatusUnprocessableEntity, "validation_failed")
+	assert.Equal(t, map[string]string{
+		"status": "invalid_status",
+		"sort":   "invalid_sort",
+		"limit":  "invalid_limit",
+	}, fieldCodes(p))
+}